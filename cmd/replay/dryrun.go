@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+)
+
+// dryRun is an http.RoundTripper that prints the GitHub API requests changing anything instead of
+// making them, and answers them as if they succeeded.
+type dryRun struct {
+	base    http.RoundTripper
+	offline bool // Answer the reading requests with 404 Not Found rather than making them.
+}
+
+// accessTokens matches the path of the installation access token requests of a GitHub App, which
+// change nothing but are needed to make the reading requests.
+var accessTokens = regexp.MustCompile(`/installations/[0-9]+/access_tokens$`)
+
+func (d *dryRun) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodGet || req.Method == http.MethodHead ||
+		req.Method == http.MethodPost && accessTokens.MatchString(req.URL.Path) {
+		if !d.offline {
+			return d.base.RoundTrip(req)
+		}
+
+		fmt.Printf("offline: %s %s\n", req.Method, req.URL.Path)
+		return respond(req, http.StatusNotFound, `{"message": "Not Found"}`), nil
+	}
+
+	var body []byte
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		body = b
+	}
+
+	fmt.Printf("dry run: %s %s %s\n", req.Method, req.URL.Path, bytes.TrimSpace(body))
+
+	// Null decodes into whatever the client expects in return.
+	return respond(req, http.StatusOK, "null"), nil
+}
+
+func respond(req *http.Request, code int, body string) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", code, http.StatusText(code)),
+		StatusCode:    code,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          ioutil.NopCloser(bytes.NewBufferString(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,233 @@
+/*
+Package main provides a command to replay recorded GitHub webhook deliveries through LGTM offline,
+e.g., to reproduce a bug with the deliveries of a production PR:
+
+	replay -config lgtm.yaml deliveries/
+
+Every delivery is a file of the request headers, a blank line and the JSON payload, as shown by the
+"Recent Deliveries" of the GitHub webhook settings:
+
+	X-GitHub-Event: issue_comment
+	X-GitHub-Delivery: 72d3162e-cc78-11e3-81ab-4c9367dc0958
+
+	{"action": "created", ...}
+
+The deliveries of a directory are replayed in the order of their file names. Each delivery is signed
+with the first configured webhook secret and handled the same way as the webhook service does,
+except that the GitHub API calls changing anything are printed rather than made. Reading calls, and
+the access token requests of a GitHub App, are still made with the configured credentials unless
+running offline. The review state given with -store is copied to a temporary file first, so that the
+replay never changes nor locks the BoltDB file of the service. Reminders, reconciliation, the audit
+log and the dedupe of redeliveries are off.
+*/
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/garukun/golgtm/pkg/http/certs"
+	"github.com/garukun/golgtm/pkg/lgtm"
+	"github.com/garukun/golgtm/pkg/lgtm/config"
+)
+
+var (
+	configFile = flag.String("config", "", "YAML or JSON configuration file; environment variables override the settings in the file")
+	storePath  = flag.String("store", "", "BoltDB file of the review state of PRs to replay on top of, which is copied rather than changed; the review state starts empty in memory if not set")
+	offline    = flag.Bool("offline", false, "Answer the GitHub API calls reading anything with 404 Not Found rather than making them")
+	timeout    = flag.Duration("timeout", time.Minute, "Time allowed for the updates of the deliveries to be applied")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] delivery-file-or-dir...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(flag.Args()); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run function replays the deliveries of the given files and directories.
+func run(args []string) error {
+	conf, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	files, err := deliveryFiles(args)
+	if err != nil {
+		return err
+	}
+
+	// Never touch the review state nor the audit log of the service, and only handle the deliveries
+	// given: no reminders, no reconciliation, and no ignoring deliveries replayed before.
+	conf.StorePath = ""
+	if len(*storePath) != 0 {
+		if conf.StorePath, err = copyStore(*storePath); err != nil {
+			return fmt.Errorf("cannot copy the review state: %v", err)
+		}
+		defer os.Remove(conf.StorePath)
+	}
+
+	conf.AuditPath = ""
+	conf.Reminders.IdleAfter = 0
+	conf.Updater.ReconcileInterval = 0
+	conf.Github.DeliveryTTL = 0
+
+	base := certs.DefaultHTTPClient
+	if len(conf.Github.CAFile) != 0 {
+		if base, err = certs.NewHTTPClient(conf.Github.CAFile); err != nil {
+			return err
+		}
+	}
+
+	c := &http.Client{Transport: &dryRun{base: base.Transport, offline: *offline}}
+	l, err := lgtm.New(c, conf)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		if err := replay(l, []byte(conf.Github.Secrets[0]), f); err != nil {
+			log.Printf("%s: %v", f, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	if err := l.Close(ctx); err != nil {
+		return fmt.Errorf("cannot finish the updates: %v", err)
+	}
+
+	return nil
+}
+
+// copyStore function copies the BoltDB file to a temporary file and returns the path of the copy,
+// which is all the replay opens: BoltDB locks the file it opens, and writes to it.
+func copyStore(path string) (string, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	dst, err := ioutil.TempFile("", "lgtm-replay-")
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(dst.Name())
+		return "", err
+	}
+
+	if err := dst.Close(); err != nil {
+		os.Remove(dst.Name())
+		return "", err
+	}
+
+	return dst.Name(), nil
+}
+
+func loadConfig() (*config.Config, error) {
+	if len(*configFile) != 0 {
+		return lgtm.ConfigFromFile(*configFile)
+	}
+
+	return lgtm.ConfigFromEnv()
+}
+
+// deliveryFiles function returns the delivery files of the given files and directories in order.
+func deliveryFiles(args []string) ([]string, error) {
+	var files []string
+	for _, arg := range args {
+		fi, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		if !fi.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+
+		infos, err := ioutil.ReadDir(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		var names []string
+		for _, info := range infos {
+			if !info.IsDir() {
+				names = append(names, filepath.Join(arg, info.Name()))
+			}
+		}
+
+		sort.Strings(names)
+		files = append(files, names...)
+	}
+
+	return files, nil
+}
+
+// replay function sends the delivery of the file through the LGTM handler and prints the response.
+func replay(h http.Handler, secret []byte, file string) error {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	r := bufio.NewReader(bytes.NewReader(b))
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		return fmt.Errorf("headers: %v", err)
+	}
+
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	body = bytes.TrimSpace(body)
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	req.Header = http.Header(header)
+
+	// Re-sign the payload with the configured secret rather than the one of the recording.
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	req.Header.Del("X-Hub-Signature")
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, req)
+
+	fmt.Printf("%s: %s %d %s %s\n", file, req.Header.Get("X-GitHub-Event"), resp.Code,
+		resp.Header().Get("X-LGTM-Response"), resp.Body.String())
+	return nil
+}
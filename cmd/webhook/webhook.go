@@ -6,22 +6,31 @@ package main
 import (
 	_ "net/http/pprof"
 
+	"context"
 	"expvar"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
 	"runtime"
 	"sync"
+	"syscall"
+	"time"
 
 	"github.com/garukun/golgtm/pkg/http/certs"
 	"github.com/garukun/golgtm/pkg/lgtm"
+	"github.com/garukun/golgtm/pkg/lgtm/config"
 )
 
 var (
 	port             = flag.Int("port", 8080, "Port on which the service will run")
 	debugPort        = flag.Int("debugport", -1, "Port to which the service will expose debug information")
 	blockProfileRate = flag.Int("blockprofilerate", 0, "Rate at which the profiler profiles for blocking contentions; see 'go doc runtime.SetBlockProfileRate'.")
+	configFile       = flag.String("config", "", "YAML or JSON configuration file; environment variables override the settings in the file")
+	shutdownTimeout  = flag.Duration("shutdowntimeout", 25*time.Second, "Time allowed on SIGTERM to finish the webhook requests and the queued GitHub updates in progress")
+	dashboard        = flag.String("dashboard", "debug", "Server on which the dashboard of the PR review states is served at /dashboard/: debug, main or none; the dashboard is unauthenticated, so only serve it on main behind an authenticating proxy")
 )
 
 var (
@@ -38,10 +47,18 @@ func init() {
 }
 
 func main() {
+	l := newLGTM()
+
+	mux := http.NewServeMux()
+	mux.Handle("/", l)
+	if *dashboard == "main" {
+		mux.Handle("/dashboard/", http.StripPrefix("/dashboard", l.Dashboard()))
+	}
+
 	servers := map[string]*http.Server{
 		"main": {
 			Addr:    fmt.Sprintf(":%d", *port),
-			Handler: lgtmHandler(),
+			Handler: mux,
 		},
 
 		// Add other servers here.
@@ -50,6 +67,10 @@ func main() {
 	// Expose debug info via debugPort.
 	if *debugPort != -1 {
 		runtime.SetBlockProfileRate(*blockProfileRate)
+		http.Handle("/admin/", http.StripPrefix("/admin", l.Admin()))
+		if *dashboard == "debug" {
+			http.Handle("/dashboard/", http.StripPrefix("/dashboard", l.Dashboard()))
+		}
 
 		servers["debug"] = &http.Server{
 			Addr:    fmt.Sprintf(":%d", *debugPort),
@@ -57,18 +78,41 @@ func main() {
 		}
 	}
 
+	for n, s := range servers {
+		go func(name string, server *http.Server) {
+			log.Printf("Starting server %s (rev:%s) on %s...", name, revision, server.Addr)
+			if err := server.ListenAndServe(); err != http.ErrServerClosed {
+				log.Fatal(err)
+			}
+		}(n, s)
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGTERM, os.Interrupt)
+	log.Printf("Received %v, shutting down...", <-sigCh)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+
+	// Stop accepting webhooks before draining the updates queued by them.
 	wg := &sync.WaitGroup{}
 	wg.Add(len(servers))
 
 	for n, s := range servers {
 		go func(name string, server *http.Server) {
-			log.Printf("Starting server %s (rev:%s) on %s...", name, revision, server.Addr)
-			log.Fatal(server.ListenAndServe())
+			if err := server.Shutdown(ctx); err != nil {
+				log.Printf("Cannot shut down server %s: %v", name, err)
+			}
 			wg.Done()
 		}(n, s)
 	}
 
 	wg.Wait()
+
+	if err := l.Close(ctx); err != nil {
+		log.Printf("Cannot finish the queued updates: %v", err)
+	}
+
 	log.Print("Bye!")
 }
 
@@ -77,11 +121,31 @@ func exposeBuildInfo() {
 	expvar.NewString("rev").Set(revision)
 }
 
-func lgtmHandler() http.Handler {
-	conf, err := lgtm.ConfigFromEnv()
+func newLGTM() *lgtm.LGTM {
+	var conf *config.Config
+	var err error
+
+	if len(*configFile) != 0 {
+		conf, err = lgtm.ConfigFromFile(*configFile)
+	} else {
+		conf, err = lgtm.ConfigFromEnv()
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	c := certs.DefaultHTTPClient
+	if len(conf.Github.CAFile) != 0 {
+		if c, err = certs.NewHTTPClient(conf.Github.CAFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	l, err := lgtm.New(c, conf)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	return lgtm.New(certs.DefaultHTTPClient, conf)
+	return l
 }
@@ -3,6 +3,8 @@ package certs
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"fmt"
+	"io/ioutil"
 	"net/http"
 )
 
@@ -13,3 +15,23 @@ func init() {
 	pool.AppendCertsFromPEM(pemCerts)
 	DefaultHTTPClient = &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
 }
+
+// NewHTTPClient function returns an http.Client trusting the PEM certificates of the given files on
+// top of the embedded ones, e.g., the CA of a GitHub Enterprise instance.
+func NewHTTPClient(caFiles ...string) (*http.Client, error) {
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(pemCerts)
+
+	for _, f := range caFiles {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("%s: no PEM certificates", f)
+		}
+	}
+
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}, nil
+}
@@ -0,0 +1,61 @@
+package certs_test
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/garukun/golgtm/pkg/http/certs"
+)
+
+func TestNewHTTPClient(t *testing.T) {
+	s := httptest.NewTLSServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {}))
+	defer s.Close()
+
+	dir, err := ioutil.TempDir("", "certs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := filepath.Join(dir, "ca.pem")
+	ioutil.WriteFile(ca, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.TLS.Certificates[0].Certificate[0]}), 0600)
+
+	notPEM := filepath.Join(dir, "ca.txt")
+	ioutil.WriteFile(notPEM, []byte("matrix"), 0600)
+
+	tests := []struct {
+		caFiles []string
+		err     bool // Creating the client.
+		trusted bool // The test server.
+	}{
+		{caFiles: nil, trusted: false},
+		{caFiles: []string{ca}, trusted: true},
+		{caFiles: []string{notPEM}, err: true},
+		{caFiles: []string{filepath.Join(dir, "missing.pem")}, err: true},
+	}
+
+	for i, test := range tests {
+		t.Logf("Testing %d...", i)
+		c, err := certs.NewHTTPClient(test.caFiles...)
+		if test.err && err == nil || !test.err && err != nil {
+			t.Errorf("The returned error %v did not meet the expectation.", err)
+			continue
+		} else if err != nil {
+			continue
+		}
+
+		resp, err := c.Get(s.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+
+		if trusted := err == nil; trusted != test.trusted {
+			t.Errorf("Expected the test server to be trusted: %t; %v.", test.trusted, err)
+		}
+	}
+}
@@ -1,41 +1,227 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 )
 
 type Config struct {
 	Github struct {
-		Secret    string `envconfig:"secret" required:"true"`
-		AuthToken string `envconfig:"auth_token" required:"true"`
-		Owner     string `envconfig:"owner" required:"true"`
-		Repo      string `envconfig:"repo" required:"true"`
-	}
+		// Secrets are the webhook secrets, any of which may sign a request, e.g., while rotating the
+		// secret.
+		Secrets []string `envconfig:"secret" required:"true" json:"secrets" yaml:"secrets"`
+
+		// AuthToken authenticates the API requests as a user, unless running as a GitHub App.
+		AuthToken string `envconfig:"auth_token" json:"auth_token" yaml:"auth_token"`
+		Repos     repos  `envconfig:"repos" required:"true" json:"repos" yaml:"repos"`
+
+		// AppID and AppKeyFile, the path of the PEM private key of the app, authenticate the API
+		// requests as the installations of a GitHub App instead of AuthToken.
+		AppID      int    `envconfig:"app_id" json:"app_id" yaml:"app_id"`
+		AppKeyFile string `envconfig:"app_key_file" json:"app_key_file" yaml:"app_key_file"`
+
+		// BaseURL and UploadURL are the API and upload URLs of a GitHub Enterprise instance, e.g.,
+		// https://github.example.com/api/v3/ and https://github.example.com/api/uploads/; github.com is
+		// used if not set.
+		BaseURL   string `envconfig:"base_url" json:"base_url" yaml:"base_url"`
+		UploadURL string `envconfig:"upload_url" json:"upload_url" yaml:"upload_url"`
+
+		// CAFile is the path of a PEM bundle of extra certificate authorities to trust, e.g., the CA
+		// of a GitHub Enterprise instance.
+		CAFile string `envconfig:"ca_file" json:"ca_file" yaml:"ca_file"`
+
+		// AllowSHA1 accepts webhook requests signed with HMAC-SHA1 only.
+		AllowSHA1 bool `envconfig:"allow_sha1" json:"allow_sha1" yaml:"allow_sha1"`
+
+		// RepoConfig enables reading the workflow of a repository from the `.lgtm.yaml` file on its
+		// default branch, which overrides the workflow configured here. It is off by default, since
+		// anyone able to push to the default branch could then loosen the policy of the repository.
+		RepoConfig bool `envconfig:"repo_config" json:"repo_config" yaml:"repo_config"`
+
+		// DeliveryTTL is how long the IDs of the webhook deliveries are remembered, and
+		// DeliveryCacheSize how many of them at most, so that redeliveries are ignored.
+		DeliveryTTL       time.Duration `envconfig:"delivery_ttl" default:"24h" json:"delivery_ttl" yaml:"delivery_ttl"`
+		DeliveryCacheSize int           `envconfig:"delivery_cache_size" default:"10000" json:"delivery_cache_size" yaml:"delivery_cache_size"`
+	} `json:"github" yaml:"github"`
+
+	// Workflow is the default workflow for every repository without its own workflow.
+	Workflow Workflow `json:"workflow" yaml:"workflow"`
+
+	Updater struct {
+		// RetryAttempts is the number of attempts of a failed GitHub API call, including the first
+		// one, before the update is kept as a dead letter.
+		RetryAttempts int           `envconfig:"retry_attempts" default:"5" json:"retry_attempts" yaml:"retry_attempts"`
+		RetryDelay    time.Duration `envconfig:"retry_delay" default:"1s" json:"retry_delay" yaml:"retry_delay"`
+		RetryMaxDelay time.Duration `envconfig:"retry_max_delay" default:"1m" json:"retry_max_delay" yaml:"retry_max_delay"`
+
+		// Checks reports the review state through a check run of the Checks API instead of a commit
+		// status; only GitHub Apps may use the Checks API.
+		Checks bool `envconfig:"checks" json:"checks" yaml:"checks"`
+
+		// TeamsTTL is how long the members of the teams required for approvals are cached.
+		TeamsTTL time.Duration `envconfig:"teams_ttl" default:"10m" json:"teams_ttl" yaml:"teams_ttl"`
+
+		// ReconcileInterval is how often the labels and the statuses of the open PRs are reconciled
+		// with their review state, starting on startup; they are never reconciled if not set.
+		ReconcileInterval time.Duration `envconfig:"reconcile_interval" json:"reconcile_interval" yaml:"reconcile_interval"`
+
+		// Workers is the number of the workers applying the updates to GitHub in parallel, and
+		// QueueSize the most updates queued for every worker before webhooks are answered with 503.
+		Workers   int `envconfig:"workers" default:"4" json:"workers" yaml:"workers"`
+		QueueSize int `envconfig:"queue_size" default:"100" json:"queue_size" yaml:"queue_size"`
+	} `json:"updater" yaml:"updater"`
+
+	Reminders struct {
+		// IdleAfter is how long a PR may sit in review without any activity before its requested
+		// reviewers are reminded; reminders are off if not set.
+		IdleAfter time.Duration `envconfig:"idle_after" json:"idle_after" yaml:"idle_after"`
+
+		// EscalateAfter is how long a PR may sit in review without any activity before the reminders
+		// also mention EscalateTo, e.g., @garukun/leads; reminders are never escalated if not set.
+		EscalateAfter time.Duration `envconfig:"escalate_after" json:"escalate_after" yaml:"escalate_after"`
+		EscalateTo    []string      `envconfig:"escalate_to" json:"escalate_to" yaml:"escalate_to"`
+
+		// Interval is the least time between two reminders on the same PR.
+		Interval time.Duration `envconfig:"interval" default:"24h" json:"interval" yaml:"interval"`
 
-	Workflow struct {
-		Context struct {
-			Name        string `envconfig:"name" default:"LGTM Code Review"`
-			Description string `envconfig:"desc" default:"LGTM Code Review workflow."`
-			URL         string `envconfig:"url" default:"https://github.com/garukun/golgtm"`
+		// PollInterval is how often the PRs in review are checked for reminders.
+		PollInterval time.Duration `envconfig:"poll_interval" default:"1h" json:"poll_interval" yaml:"poll_interval"`
+	} `json:"reminders" yaml:"reminders"`
+
+	// StorePath is the path of the BoltDB file that persists the review state of PRs; the review
+	// state is only kept in memory if not set.
+	StorePath string `envconfig:"store_path" json:"store_path" yaml:"store_path"`
+
+	// AuditPath is the path of the file the review decisions are appended to as JSON lines; the
+	// review decisions are not audited if not set.
+	AuditPath string `envconfig:"audit_path" json:"audit_path" yaml:"audit_path"`
+
+	// AdminToken authenticates the requests to the administrative API as bearer tokens; the routes
+	// changing the review state of PRs are refused if not set.
+	AdminToken string `envconfig:"admin_token" json:"admin_token" yaml:"admin_token"`
+}
+
+type Workflow struct {
+	Context struct {
+		Name        string `envconfig:"name" default:"LGTM Code Review" json:"name" yaml:"name"`
+		Description string `envconfig:"desc" default:"LGTM Code Review workflow." json:"desc" yaml:"desc"`
+		URL         string `envconfig:"url" default:"https://github.com/garukun/golgtm" json:"url" yaml:"url"`
+	} `json:"context" yaml:"context"`
+
+	InReview struct {
+		Label    string   `envconfig:"label" default:"Needs Review" json:"label" yaml:"label"`
+		Triggers Triggers `envconfig:"trigger" default:"ptal:1,please review:1,:-1::1" json:"triggers" yaml:"triggers"`
+	} `json:"in_review" yaml:"in_review"`
+
+	Approved struct {
+		Label    string   `envconfig:"label" default:"Ready" json:"label" yaml:"label"`
+		Triggers Triggers `envconfig:"trigger" default:"lgtm:1,:+1::1" json:"triggers" yaml:"triggers"`
+
+		// Owners requires an approval from the OWNERS files of every directory with changed files;
+		// only the approvers listed by the OWNERS files are counted towards the triggers.
+		Owners bool `envconfig:"owners" json:"owners" yaml:"owners"`
+
+		// CodeOwners requires an approval from an owner of every CODEOWNERS pattern matching the changed
+		// files; only the code owners are counted towards the triggers.
+		CodeOwners bool `envconfig:"codeowners" json:"codeowners" yaml:"codeowners"`
+
+		// Teams requires approvals from the members of every listed team on top of the triggers.
+		Teams TeamRequirements `envconfig:"teams" json:"teams" yaml:"teams"`
+	} `json:"approved" yaml:"approved"`
+}
+
+// Repo method returns the configured repository of the given owner and name, and whether the
+// repository is allowed to be served at all.
+func (c *Config) Repo(owner, name string) (*Repo, bool) {
+	for i, r := range c.Github.Repos {
+		if strings.EqualFold(r.Owner, owner) && strings.EqualFold(r.Name, name) {
+			return &c.Github.Repos[i], true
 		}
+	}
+
+	return nil, false
+}
+
+// RepoWorkflow method returns the workflow of the given repository, falling back to the default
+// workflow if the repository does not have its own.
+func (c *Config) RepoWorkflow(owner, name string) *Workflow {
+	if r, ok := c.Repo(owner, name); ok && r.Workflow != nil {
+		return r.Workflow
+	}
+
+	return &c.Workflow
+}
 
-		InReview struct {
-			Label   string  `envconfig:"label" default:"Needs Review"`
-			Trigger trigger `envconfig:"trigger" default:"ptal:1,please review:1,:-1::1"`
+// Repo is a GitHub repository served by LGTM.
+type Repo struct {
+	Owner string `json:"owner" yaml:"owner"`
+	Name  string `json:"name" yaml:"name"`
+
+	// Workflow overrides the default workflow for the repository; nil to use the default.
+	Workflow *Workflow `json:"workflow,omitempty" yaml:"workflow,omitempty"`
+}
+
+// FullName method returns the repository name in the form of `<owner>/<name>`.
+func (r Repo) FullName() string {
+	return r.Owner + "/" + r.Name
+}
+
+// envPrefix method returns the environment variable prefix of the repository workflow, e.g.,
+// `lgtm_repo_garukun_golgtm` for garukun/golgtm. Characters that cannot be part of an environment
+// variable name are replaced by `_`.
+func (r Repo) envPrefix() string {
+	return "lgtm_repo_" + strings.Map(func(c rune) rune {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+			return c
+		default:
+			return '_'
 		}
+	}, r.Owner+"_"+r.Name)
+}
 
-		Approved struct {
-			Label   string  `envconfig:"label" default:"Ready"`
-			Trigger trigger `envconfig:"trigger" default:"lgtm:1,:+1::1"`
+// repos method implements an envconfig.Decoder interface to provide the allowlist of repositories.
+//
+// Format:
+// 	<owner>/<repo>[,<owner>/<repo>]
+type repos []Repo
+
+func (r *repos) Decode(value string) error {
+	if len(value) == 0 {
+		return nil
+	}
+
+	var tmp repos
+	for _, fullName := range strings.Split(value, ",") {
+		fullName = strings.TrimSpace(fullName)
+		sep := strings.Index(fullName, "/")
+		if sep <= 0 || sep+1 == len(fullName) || strings.Count(fullName, "/") != 1 {
+			return fmt.Errorf("Invalid repository format %s around %s", value, fullName)
 		}
+
+		tmp = append(tmp, Repo{Owner: fullName[:sep], Name: fullName[sep+1:]})
 	}
+
+	*r = tmp
+	return nil
+}
+
+// Trigger is a phrase that moves a PR to another state once enough distinct commenters have said it.
+type Trigger struct {
+	Phrase string `json:"phrase" yaml:"phrase"`
+
+	// Count is the number of distinct commenters who must say the phrase on a PR before the trigger
+	// fires, e.g., 2 for `lgtm` requires two reviewers.
+	Count int `json:"count" yaml:"count"`
 }
 
-// trigger method implements an envconfig.Decoder interface to provide a custom environment variable
+// Triggers type implements an envconfig.Decoder interface to provide a custom environment variable
 // deserialization format.
 //
 // Format:
@@ -44,14 +230,16 @@ type Config struct {
 // Trigger phrase can be any string literal except the `,` character; string literal does not need
 // to be escaped in any way including the `:` character;
 // Trigger number must be a positive integer.
-type trigger map[string]int
+//
+// Configuration files should list the triggers as objects instead, which has no such limitation.
+type Triggers []Trigger
 
-func (t *trigger) Decode(value string) error {
+func (t *Triggers) Decode(value string) error {
 	if len(value) == 0 {
 		return nil
 	}
 
-	tmp := make(trigger)
+	var tmp Triggers
 	triggers := strings.Split(value, ",")
 	for _, trig := range triggers {
 		sep := strings.LastIndex(trig, ":")
@@ -65,14 +253,114 @@ func (t *trigger) Decode(value string) error {
 			return fmt.Errorf("Invalid trigger format %s around %s,\n%v", value, trig, err)
 		}
 
-		tmp[phrase] = count
+		if count < 1 {
+			return fmt.Errorf("Invalid trigger count %s around %s", value, trig)
+		}
+
+		tmp = append(tmp, Trigger{Phrase: phrase, Count: count})
 	}
 
 	*t = tmp
 	return nil
 }
 
+// Match method returns the first trigger whose phrase the given comment starts or ends with,
+// regardless of case.
+func (t Triggers) Match(comment string) (Trigger, bool) {
+	comment = strings.ToLower(strings.TrimSpace(comment))
+	for _, trig := range t {
+		phrase := strings.ToLower(trig.Phrase)
+		if strings.HasPrefix(comment, phrase) || strings.HasSuffix(comment, phrase) {
+			return trig, true
+		}
+	}
+
+	return Trigger{}, false
+}
+
+// TeamRequirement requires approvals from a number of members of a team.
+type TeamRequirement struct {
+	// Team is the organization and the slug of the team, e.g., garukun/platform-team.
+	Team string `json:"team" yaml:"team"`
+
+	// Count is the number of team members who must approve; one if not set.
+	Count int `json:"count" yaml:"count"`
+}
+
+// Org method returns the organization and the slug of the team.
+func (t TeamRequirement) Org() (string, string) {
+	sep := strings.Index(t.Team, "/")
+	if sep < 0 {
+		return "", t.Team
+	}
+
+	return t.Team[:sep], t.Team[sep+1:]
+}
+
+// Approvals method returns the number of approvals required from the team.
+func (t TeamRequirement) Approvals() int {
+	if t.Count < 1 {
+		return 1
+	}
+
+	return t.Count
+}
+
+// TeamRequirements type implements an envconfig.Decoder interface to provide a custom environment
+// variable deserialization format.
+//
+// Format:
+// 	<org>/<team slug>[:<count>][,<org>/<team slug>[:<count>]]
+//
+// The count is 1 if omitted.
+type TeamRequirements []TeamRequirement
+
+func (t *TeamRequirements) Decode(value string) error {
+	if len(value) == 0 {
+		return nil
+	}
+
+	var tmp TeamRequirements
+	for _, req := range strings.Split(value, ",") {
+		req = strings.TrimSpace(req)
+		team, count := req, 1
+
+		if sep := strings.LastIndex(req, ":"); sep >= 0 {
+			n, err := strconv.Atoi(req[sep+1:])
+			if err != nil || n < 1 {
+				return fmt.Errorf("Invalid team count %s around %s", value, req)
+			}
+
+			team, count = req[:sep], n
+		}
+
+		tmp = append(tmp, TeamRequirement{Team: team, Count: count})
+	}
+
+	if err := tmp.validate(); err != nil {
+		return err
+	}
+
+	*t = tmp
+	return nil
+}
+
+func (t TeamRequirements) validate() error {
+	for _, req := range t {
+		if org, slug := req.Org(); len(org) == 0 || len(slug) == 0 || strings.Count(req.Team, "/") != 1 {
+			return fmt.Errorf("Invalid team %s, expecting org/team", req.Team)
+		}
+	}
+
+	return nil
+}
+
 // NewFromEnv method retrieves the Config object from the environment variables.
+//
+// A repository may override the default workflow with environment variables prefixed by its own
+// name, e.g., `LGTM_REPO_GARUKUN_GOLGTM_APPROVED_LABEL` for garukun/golgtm. Workflow settings that
+// are not overridden this way take the built-in defaults rather than the ones of the default
+// workflow.
 func NewFromEnv() (*Config, error) {
 	c := &Config{}
 
@@ -80,5 +368,72 @@ func NewFromEnv() (*Config, error) {
 		return nil, err
 	}
 
+	for i, r := range c.Github.Repos {
+		prefix := r.envPrefix()
+		if !hasEnvPrefix(prefix) {
+			continue
+		}
+
+		w := &Workflow{}
+		if err := envconfig.Process(prefix, w); err != nil {
+			return nil, fmt.Errorf("%s: %v", r.FullName(), err)
+		}
+
+		c.Github.Repos[i].Workflow = w
+	}
+
+	if err := c.checkAuth(); err != nil {
+		return nil, err
+	}
+
+	if err := c.checkSecrets(); err != nil {
+		return nil, err
+	}
+
 	return c, nil
 }
+
+// checkAuth method checks that the API requests are authenticated either as a user or as a GitHub
+// App, and as a GitHub App if the Checks API is used.
+func (c *Config) checkAuth() error {
+	app := c.Github.AppID != 0 || len(c.Github.AppKeyFile) != 0
+	switch {
+	case app && (c.Github.AppID == 0 || len(c.Github.AppKeyFile) == 0):
+		return errors.New("both app_id and app_key_file are required for a GitHub App")
+	case !app && len(c.Github.AuthToken) == 0:
+		return errors.New("either auth_token or app_id and app_key_file are required")
+	case !app && c.Updater.Checks:
+		return errors.New("checks require app_id and app_key_file; only GitHub Apps may use the Checks API")
+	}
+
+	return nil
+}
+
+// checkSecrets method checks that there is a webhook secret and that none is empty, e.g., that a
+// trailing comma of LGTM_GITHUB_SECRET does not let an empty secret, which anyone can sign with,
+// through.
+func (c *Config) checkSecrets() error {
+	if len(c.Github.Secrets) == 0 {
+		return errors.New("a webhook secret is required")
+	}
+
+	for i, secret := range c.Github.Secrets {
+		if len(strings.TrimSpace(secret)) == 0 {
+			return fmt.Errorf("empty webhook secret at index %d", i)
+		}
+	}
+
+	return nil
+}
+
+// hasEnvPrefix function returns whether any environment variable starts with the given prefix.
+func hasEnvPrefix(prefix string) bool {
+	prefix = strings.ToUpper(prefix) + "_"
+	for _, kv := range os.Environ() {
+		if strings.HasPrefix(kv, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
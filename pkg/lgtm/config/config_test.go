@@ -1,9 +1,13 @@
 package config_test
 
 import (
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/garukun/golgtm/pkg/lgtm/config"
 )
@@ -20,17 +24,18 @@ func TestConfigFromEnv(t *testing.T) {
 			env: map[string]string{
 				"LGTM_GITHUB_SECRET":     "matrix",
 				"LGTM_GITHUB_AUTH_TOKEN": "keymaker",
-				"LGTM_GITHUB_OWNER":      "garukun",
-				"LGTM_GITHUB_REPO":       "golgtm",
+				"LGTM_GITHUB_REPOS":      "garukun/golgtm",
 			},
 			conf: &config.Config{
 				Github: config.ConfigGithub{
-					Secret:    "matrix",
+					Secrets:   []string{"matrix"},
 					AuthToken: "keymaker",
-					Owner:     "garukun",
-					Repo:      "golgtm",
+					Repos:     config.NewRepos(config.Repo{Owner: "garukun", Name: "golgtm"}),
+
+					DeliveryTTL:       24 * time.Hour,
+					DeliveryCacheSize: 10000,
 				},
-				Workflow: config.ConfigWorkflow{
+				Workflow: config.Workflow{
 					Context: config.ConfigWorkflowContext{
 						Name:        "LGTM Code Review",
 						Description: "LGTM Code Review workflow.",
@@ -39,43 +44,58 @@ func TestConfigFromEnv(t *testing.T) {
 
 					InReview: config.ConfigWorkflowInReview{
 						Label: "Needs Review",
-						Trigger: config.NewTrigger(map[string]int{
-							"ptal":          1,
-							"please review": 1,
-							":-1:":          1,
-						}),
+						Triggers: config.Triggers{
+							{Phrase: "ptal", Count: 1},
+							{Phrase: "please review", Count: 1},
+							{Phrase: ":-1:", Count: 1},
+						},
 					},
 
 					Approved: config.ConfigWorkflowApproved{
 						Label: "Ready",
-						Trigger: config.NewTrigger(map[string]int{
-							"lgtm": 1,
-							":+1:": 1,
-						}),
+						Triggers: config.Triggers{
+							{Phrase: "lgtm", Count: 1},
+							{Phrase: ":+1:", Count: 1},
+						},
 					},
 				},
+
+				Updater: config.ConfigUpdater{
+					RetryAttempts: 5,
+					RetryDelay:    time.Second,
+					RetryMaxDelay: time.Minute,
+					TeamsTTL:      10 * time.Minute,
+					Workers:       4,
+					QueueSize:     100,
+				},
+
+				Reminders: config.ConfigReminders{
+					Interval:     24 * time.Hour,
+					PollInterval: time.Hour,
+				},
 			},
 		},
 		// Custom values
 		{
 			err: false,
 			env: map[string]string{
-				"LGTM_GITHUB_SECRET":             "matrix",
+				"LGTM_GITHUB_SECRET":             "matrix,zion",
 				"LGTM_GITHUB_AUTH_TOKEN":         "keymaker",
-				"LGTM_GITHUB_OWNER":              "garukun",
-				"LGTM_GITHUB_REPO":               "golgtm",
+				"LGTM_GITHUB_REPOS":              "garukun/golgtm",
 				"LGTM_WORKFLOW_CONTEXT_NAME":     "custom context",
 				"LGTM_WORKFLOW_INREVIEW_LABEL":   "custom label",
 				"LGTM_WORKFLOW_INREVIEW_TRIGGER": "trigger1:1,trigger 2:2",
 			},
 			conf: &config.Config{
 				Github: config.ConfigGithub{
-					Secret:    "matrix",
+					Secrets:   []string{"matrix", "zion"},
 					AuthToken: "keymaker",
-					Owner:     "garukun",
-					Repo:      "golgtm",
+					Repos:     config.NewRepos(config.Repo{Owner: "garukun", Name: "golgtm"}),
+
+					DeliveryTTL:       24 * time.Hour,
+					DeliveryCacheSize: 10000,
 				},
-				Workflow: config.ConfigWorkflow{
+				Workflow: config.Workflow{
 					Context: config.ConfigWorkflowContext{
 						Name:        "custom context",
 						Description: "LGTM Code Review workflow.",
@@ -84,44 +104,106 @@ func TestConfigFromEnv(t *testing.T) {
 
 					InReview: config.ConfigWorkflowInReview{
 						Label: "custom label",
-						Trigger: config.NewTrigger(map[string]int{
-							"trigger1":  1,
-							"trigger 2": 2,
-						}),
+						Triggers: config.Triggers{
+							{Phrase: "trigger1", Count: 1},
+							{Phrase: "trigger 2", Count: 2},
+						},
 					},
 
 					Approved: config.ConfigWorkflowApproved{
 						Label: "Ready",
-						Trigger: config.NewTrigger(map[string]int{
-							"lgtm": 1,
-							":+1:": 1,
-						}),
+						Triggers: config.Triggers{
+							{Phrase: "lgtm", Count: 1},
+							{Phrase: ":+1:", Count: 1},
+						},
 					},
 				},
+
+				Updater: config.ConfigUpdater{
+					RetryAttempts: 5,
+					RetryDelay:    time.Second,
+					RetryMaxDelay: time.Minute,
+					TeamsTTL:      10 * time.Minute,
+					Workers:       4,
+					QueueSize:     100,
+				},
+
+				Reminders: config.ConfigReminders{
+					Interval:     24 * time.Hour,
+					PollInterval: time.Hour,
+				},
 			},
 		},
+		// Non-positive trigger count
+		{
+			err: true,
+			env: map[string]string{
+				"LGTM_GITHUB_SECRET":             "matrix",
+				"LGTM_GITHUB_AUTH_TOKEN":         "keymaker",
+				"LGTM_GITHUB_REPOS":              "garukun/golgtm",
+				"LGTM_WORKFLOW_APPROVED_TRIGGER": "lgtm:0",
+			},
+			conf: nil,
+		},
+		// Invalid repository
+		{
+			err: true,
+			env: map[string]string{
+				"LGTM_GITHUB_SECRET":     "matrix",
+				"LGTM_GITHUB_AUTH_TOKEN": "keymaker",
+				"LGTM_GITHUB_REPOS":      "garukun",
+			},
+			conf: nil,
+		},
 		// Missing required values
 		{
 			err: true,
 			env: map[string]string{
 				"LGTM_GITHUB_SECRET": "matrix",
-				"LGTM_GITHUB_OWNER":  "garukun",
-				"LGTM_GITHUB_REPO":   "golgtm",
+				"LGTM_GITHUB_REPOS":  "garukun/golgtm",
+			},
+			conf: nil,
+		},
+		// GitHub App missing its private key
+		{
+			err: true,
+			env: map[string]string{
+				"LGTM_GITHUB_SECRET": "matrix",
+				"LGTM_GITHUB_REPOS":  "garukun/golgtm",
+				"LGTM_GITHUB_APP_ID": "42",
+			},
+			conf: nil,
+		},
+		// Check runs without a GitHub App
+		{
+			err: true,
+			env: map[string]string{
+				"LGTM_GITHUB_SECRET":     "matrix",
+				"LGTM_GITHUB_AUTH_TOKEN": "keymaker",
+				"LGTM_GITHUB_REPOS":      "garukun/golgtm",
+				"LGTM_UPDATER_CHECKS":    "true",
+			},
+			conf: nil,
+		},
+		// Empty secrets
+		{
+			err: true,
+			env: map[string]string{
+				"LGTM_GITHUB_SECRET":     "matrix,",
+				"LGTM_GITHUB_AUTH_TOKEN": "keymaker",
+				"LGTM_GITHUB_REPOS":      "garukun/golgtm",
+			},
+			conf: nil,
+		},
+		{
+			err: true,
+			env: map[string]string{
+				"LGTM_GITHUB_SECRET":     " ",
+				"LGTM_GITHUB_AUTH_TOKEN": "keymaker",
+				"LGTM_GITHUB_REPOS":      "garukun/golgtm",
 			},
 			conf: nil,
 		},
-	}
-
-	withTestEnv := func(env map[string]string, fn func()) {
-		for k, v := range env {
-			os.Setenv(k, v)
-		}
-
-		fn()
-
-		for k := range env {
-			os.Unsetenv(k)
-		}
 	}
 
 	for i, test := range tests {
@@ -138,3 +220,292 @@ func TestConfigFromEnv(t *testing.T) {
 		})
 	}
 }
+
+func TestRepoWorkflowFromEnv(t *testing.T) {
+	env := map[string]string{
+		"LGTM_GITHUB_SECRET":                       "matrix",
+		"LGTM_GITHUB_AUTH_TOKEN":                   "keymaker",
+		"LGTM_GITHUB_REPOS":                        "garukun/golgtm, garukun/go-lgtm",
+		"LGTM_WORKFLOW_APPROVED_LABEL":             "default label",
+		"LGTM_REPO_GARUKUN_GO_LGTM_APPROVED_LABEL": "custom label",
+	}
+
+	tests := []struct {
+		owner string
+		name  string
+		ok    bool
+		label string
+	}{
+		{owner: "garukun", name: "golgtm", ok: true, label: "default label"},
+		{owner: "Garukun", name: "GoLGTM", ok: true, label: "default label"},
+		{owner: "garukun", name: "go-lgtm", ok: true, label: "custom label"},
+		{owner: "garukun", name: "matrix", ok: false, label: "default label"},
+	}
+
+	withTestEnv(env, func() {
+		conf, err := config.NewFromEnv()
+		if err != nil {
+			t.Fatalf("Unexpected error %v.", err)
+		}
+
+		for i, test := range tests {
+			t.Logf("Testing %d...", i)
+			if _, ok := conf.Repo(test.owner, test.name); ok != test.ok {
+				t.Errorf("Expected %s/%s to be allowed: %t.", test.owner, test.name, test.ok)
+			}
+
+			if label := conf.RepoWorkflow(test.owner, test.name).Approved.Label; label != test.label {
+				t.Errorf("Expected %s/%s approved label %s instead of %s.", test.owner, test.name, test.label, label)
+			}
+		}
+	})
+}
+
+func TestConfigFromFile(t *testing.T) {
+	const yamlConf = `
+github:
+  secrets:
+  - matrix
+  auth_token: keymaker
+  repos:
+  - owner: garukun
+    name: golgtm
+  - owner: garukun
+    name: go-lgtm
+    workflow:
+      approved:
+        triggers:
+        - phrase: lgtm, thanks
+          count: 2
+workflow:
+  approved:
+    label: file label
+`
+
+	const jsonConf = `{
+  "github": {
+    "secrets": ["matrix"],
+    "auth_token": "keymaker",
+    "delivery_ttl": "30m",
+    "repos": [{"owner": "garukun", "name": "golgtm"}, {"owner": "garukun", "name": "go-lgtm"}]
+  },
+  "updater": {"retry_attempts": 3, "retry_delay": "2s"}
+}`
+
+	tests := []struct {
+		err      bool
+		file     string
+		content  string
+		env      map[string]string
+		label    string // Default workflow approved label.
+		repoDesc string // garukun/go-lgtm context description.
+		triggers config.Triggers
+		delay    time.Duration // Updater retry delay.
+		owners   bool          // garukun/go-lgtm owners requirement.
+	}{
+		// YAML file with a repository workflow inheriting the default workflow.
+		{
+			file:     "lgtm.yaml",
+			content:  yamlConf,
+			label:    "file label",
+			repoDesc: "LGTM Code Review workflow.",
+			triggers: config.Triggers{{Phrase: "lgtm, thanks", Count: 2}},
+			delay:    time.Second,
+		},
+		// Repository workflows inherit the default workflow...
+		{
+			file:     "lgtm.yaml",
+			content:  yamlConf + "    owners: true\n",
+			label:    "file label",
+			repoDesc: "LGTM Code Review workflow.",
+			triggers: config.Triggers{{Phrase: "lgtm, thanks", Count: 2}},
+			delay:    time.Second,
+			owners:   true,
+		},
+		// ...unless they override it, even with zero values.
+		{
+			file:     "lgtm.yaml",
+			content:  strings.Replace(yamlConf, "      approved:\n", "      approved:\n        owners: false\n", 1) + "    owners: true\n",
+			label:    "file label",
+			repoDesc: "LGTM Code Review workflow.",
+			triggers: config.Triggers{{Phrase: "lgtm, thanks", Count: 2}},
+			delay:    time.Second,
+		},
+		// Environment variables override the file.
+		{
+			file:    "lgtm.yaml",
+			content: yamlConf,
+			env: map[string]string{
+				"LGTM_WORKFLOW_APPROVED_LABEL":               "env label",
+				"LGTM_WORKFLOW_CONTEXT_DESC":                 "env desc",
+				"LGTM_REPO_GARUKUN_GO_LGTM_APPROVED_TRIGGER": "lgtm:3",
+			},
+			label:    "env label",
+			repoDesc: "env desc",
+			triggers: config.Triggers{{Phrase: "lgtm", Count: 3}},
+			delay:    time.Second,
+		},
+		// JSON file with defaults, and durations given as strings.
+		{
+			file:     "lgtm.json",
+			content:  jsonConf,
+			label:    "Ready",
+			repoDesc: "LGTM Code Review workflow.",
+			triggers: config.Triggers{{Phrase: "lgtm", Count: 1}, {Phrase: ":+1:", Count: 1}},
+			delay:    2 * time.Second,
+		},
+		// Missing required values
+		{
+			err:     true,
+			file:    "lgtm.yaml",
+			content: "github:\n  secrets:\n  - matrix\n",
+		},
+		// Empty secret
+		{
+			err:     true,
+			file:    "lgtm.yaml",
+			content: strings.Replace(yamlConf, "  - matrix\n", "  - matrix\n  - \"\"\n", 1),
+		},
+		// Invalid trigger
+		{
+			err:     true,
+			file:    "lgtm.yaml",
+			content: yamlConf + "  in_review:\n    triggers:\n    - phrase: ptal\n      count: -1\n",
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "lgtm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for i, test := range tests {
+		t.Logf("Testing %d...", i)
+		path := filepath.Join(dir, test.file)
+		if err := ioutil.WriteFile(path, []byte(test.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		withTestEnv(test.env, func() {
+			conf, err := config.NewFromFile(path)
+			if test.err && err == nil || !test.err && err != nil {
+				t.Errorf("The returned error %v did not meet the expectation.", err)
+				return
+			} else if err != nil {
+				return
+			}
+
+			if label := conf.Workflow.Approved.Label; label != test.label {
+				t.Errorf("Expected default approved label %s instead of %s.", test.label, label)
+			}
+
+			w := conf.RepoWorkflow("garukun", "go-lgtm")
+			if desc := w.Context.Description; desc != test.repoDesc {
+				t.Errorf("Expected repository context description %s instead of %s.", test.repoDesc, desc)
+			}
+
+			if !reflect.DeepEqual(w.Approved.Triggers, test.triggers) {
+				t.Errorf("Expected repository approved triggers %v instead of %v.", test.triggers, w.Approved.Triggers)
+			}
+
+			if delay := conf.Updater.RetryDelay; delay != test.delay {
+				t.Errorf("Expected retry delay %v instead of %v.", test.delay, delay)
+			}
+
+			if w.Approved.Owners != test.owners {
+				t.Errorf("Expected repository owners requirement %t instead of %t.", test.owners, w.Approved.Owners)
+			}
+		})
+	}
+}
+
+func TestParseWorkflow(t *testing.T) {
+	base := &config.Workflow{}
+	base.InReview.Label = "Needs Review"
+	base.Approved.Label = "Ready"
+	base.Approved.Triggers = config.Triggers{{Phrase: "lgtm", Count: 1}}
+
+	tests := []struct {
+		err      bool
+		content  string
+		label    string
+		triggers config.Triggers
+	}{
+		// Overrides only the listed settings.
+		{
+			content:  "approved:\n  triggers:\n  - phrase: ship it\n    count: 2\n",
+			label:    "Ready",
+			triggers: config.Triggers{{Phrase: "ship it", Count: 2}},
+		},
+		{
+			content:  "approved:\n  label: LGTM\n",
+			label:    "LGTM",
+			triggers: config.Triggers{{Phrase: "lgtm", Count: 1}},
+		},
+		// Invalid trigger
+		{
+			err:     true,
+			content: "approved:\n  triggers:\n  - phrase: ship it\n",
+		},
+	}
+
+	for i, test := range tests {
+		t.Logf("Testing %d...", i)
+		w, err := config.ParseWorkflow([]byte(test.content), base)
+		if test.err && err == nil || !test.err && err != nil {
+			t.Errorf("The returned error %v did not meet the expectation.", err)
+			continue
+		} else if err != nil {
+			continue
+		}
+
+		if w.Approved.Label != test.label || !reflect.DeepEqual(w.Approved.Triggers, test.triggers) {
+			t.Errorf("Expected approved label %s and triggers %v instead of %s and %v.", test.label, test.triggers, w.Approved.Label, w.Approved.Triggers)
+		}
+
+		if w.InReview.Label != base.InReview.Label {
+			t.Errorf("Expected in review label %s to be kept instead of %s.", base.InReview.Label, w.InReview.Label)
+		}
+	}
+}
+
+func TestTeamRequirementsDecode(t *testing.T) {
+	tests := []struct {
+		err   bool
+		value string
+		teams config.TeamRequirements
+	}{
+		{value: "", teams: nil},
+		{value: "garukun/platform", teams: config.TeamRequirements{{Team: "garukun/platform", Count: 1}}},
+		{
+			value: "garukun/platform:2, garukun/security",
+			teams: config.TeamRequirements{{Team: "garukun/platform", Count: 2}, {Team: "garukun/security", Count: 1}},
+		},
+		{err: true, value: "platform"},
+		{err: true, value: "garukun/platform:0"},
+		{err: true, value: "garukun/platform/x"},
+	}
+
+	for i, test := range tests {
+		t.Logf("Testing %d...", i)
+		var teams config.TeamRequirements
+		if err := teams.Decode(test.value); test.err && err == nil || !test.err && err != nil {
+			t.Errorf("The returned error %v did not meet the expectation.", err)
+		} else if err == nil && !reflect.DeepEqual(teams, test.teams) {
+			t.Errorf("Expected teams %v instead of %v.", test.teams, teams)
+		}
+	}
+}
+
+func withTestEnv(env map[string]string, fn func()) {
+	for k, v := range env {
+		os.Setenv(k, v)
+	}
+
+	fn()
+
+	for k := range env {
+		os.Unsetenv(k)
+	}
+}
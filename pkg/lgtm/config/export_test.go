@@ -1,7 +1,9 @@
 package config
 
-func NewTrigger(t map[string]int) trigger {
-	return trigger(t)
+import "time"
+
+func NewRepos(r ...Repo) repos {
+	return repos(r)
 }
 
 /*
@@ -9,42 +11,70 @@ Composite struct literal mapping for testing.
 */
 
 type ConfigGithub struct {
-	Secret    string `envconfig:"secret" required:"true"`
-	AuthToken string `envconfig:"auth_token" required:"true"`
-	Owner     string `envconfig:"owner" required:"true"`
-	Repo      string `envconfig:"repo" required:"true"`
-}
+	Secrets []string `envconfig:"secret" required:"true" json:"secrets" yaml:"secrets"`
+
+	AuthToken string `envconfig:"auth_token" json:"auth_token" yaml:"auth_token"`
+	Repos     repos  `envconfig:"repos" required:"true" json:"repos" yaml:"repos"`
+
+	AppID      int    `envconfig:"app_id" json:"app_id" yaml:"app_id"`
+	AppKeyFile string `envconfig:"app_key_file" json:"app_key_file" yaml:"app_key_file"`
+
+	BaseURL   string `envconfig:"base_url" json:"base_url" yaml:"base_url"`
+	UploadURL string `envconfig:"upload_url" json:"upload_url" yaml:"upload_url"`
+	CAFile    string `envconfig:"ca_file" json:"ca_file" yaml:"ca_file"`
+
+	AllowSHA1 bool `envconfig:"allow_sha1" json:"allow_sha1" yaml:"allow_sha1"`
 
-type ConfigWorkflow struct {
-	Context struct {
-		Name        string `envconfig:"name" default:"LGTM Code Review"`
-		Description string `envconfig:"desc" default:"LGTM Code Review workflow."`
-		URL         string `envconfig:"url" default:"https://github.com/garukun/golgtm"`
-	}
-
-	InReview struct {
-		Label   string  `envconfig:"label" default:"Needs Review"`
-		Trigger trigger `envconfig:"trigger" default:"ptal:1,please review:1,:-1::1"`
-	}
-
-	Approved struct {
-		Label   string  `envconfig:"label" default:"Ready"`
-		Trigger trigger `envconfig:"trigger" default:"lgtm:1,:+1::1"`
-	}
+	RepoConfig bool `envconfig:"repo_config" json:"repo_config" yaml:"repo_config"`
+
+	DeliveryTTL       time.Duration `envconfig:"delivery_ttl" default:"24h" json:"delivery_ttl" yaml:"delivery_ttl"`
+	DeliveryCacheSize int           `envconfig:"delivery_cache_size" default:"10000" json:"delivery_cache_size" yaml:"delivery_cache_size"`
 }
 
 type ConfigWorkflowContext struct {
-	Name        string `envconfig:"name" default:"LGTM Code Review"`
-	Description string `envconfig:"desc" default:"LGTM Code Review workflow."`
-	URL         string `envconfig:"url" default:"https://github.com/garukun/golgtm"`
+	Name        string `envconfig:"name" default:"LGTM Code Review" json:"name" yaml:"name"`
+	Description string `envconfig:"desc" default:"LGTM Code Review workflow." json:"desc" yaml:"desc"`
+	URL         string `envconfig:"url" default:"https://github.com/garukun/golgtm" json:"url" yaml:"url"`
 }
 
 type ConfigWorkflowInReview struct {
-	Label   string  `envconfig:"label" default:"Needs Review"`
-	Trigger trigger `envconfig:"trigger" default:"ptal:1,please review:1,:-1::1"`
+	Label    string   `envconfig:"label" default:"Needs Review" json:"label" yaml:"label"`
+	Triggers Triggers `envconfig:"trigger" default:"ptal:1,please review:1,:-1::1" json:"triggers" yaml:"triggers"`
 }
 
 type ConfigWorkflowApproved struct {
-	Label   string  `envconfig:"label" default:"Ready"`
-	Trigger trigger `envconfig:"trigger" default:"lgtm:1,:+1::1"`
+	Label    string   `envconfig:"label" default:"Ready" json:"label" yaml:"label"`
+	Triggers Triggers `envconfig:"trigger" default:"lgtm:1,:+1::1" json:"triggers" yaml:"triggers"`
+
+	Owners bool `envconfig:"owners" json:"owners" yaml:"owners"`
+
+	CodeOwners bool `envconfig:"codeowners" json:"codeowners" yaml:"codeowners"`
+
+	Teams TeamRequirements `envconfig:"teams" json:"teams" yaml:"teams"`
+}
+
+type ConfigUpdater struct {
+	RetryAttempts int           `envconfig:"retry_attempts" default:"5" json:"retry_attempts" yaml:"retry_attempts"`
+	RetryDelay    time.Duration `envconfig:"retry_delay" default:"1s" json:"retry_delay" yaml:"retry_delay"`
+	RetryMaxDelay time.Duration `envconfig:"retry_max_delay" default:"1m" json:"retry_max_delay" yaml:"retry_max_delay"`
+
+	Checks bool `envconfig:"checks" json:"checks" yaml:"checks"`
+
+	TeamsTTL time.Duration `envconfig:"teams_ttl" default:"10m" json:"teams_ttl" yaml:"teams_ttl"`
+
+	ReconcileInterval time.Duration `envconfig:"reconcile_interval" json:"reconcile_interval" yaml:"reconcile_interval"`
+
+	Workers   int `envconfig:"workers" default:"4" json:"workers" yaml:"workers"`
+	QueueSize int `envconfig:"queue_size" default:"100" json:"queue_size" yaml:"queue_size"`
+}
+
+type ConfigReminders struct {
+	IdleAfter time.Duration `envconfig:"idle_after" json:"idle_after" yaml:"idle_after"`
+
+	EscalateAfter time.Duration `envconfig:"escalate_after" json:"escalate_after" yaml:"escalate_after"`
+	EscalateTo    []string      `envconfig:"escalate_to" json:"escalate_to" yaml:"escalate_to"`
+
+	Interval time.Duration `envconfig:"interval" default:"24h" json:"interval" yaml:"interval"`
+
+	PollInterval time.Duration `envconfig:"poll_interval" default:"1h" json:"poll_interval" yaml:"poll_interval"`
 }
@@ -0,0 +1,322 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// decoder is the same as envconfig.Decoder; custom types decode their own environment variables.
+type decoder interface {
+	Decode(value string) error
+}
+
+// NewFromFile method retrieves the Config object from a YAML or JSON file, depending on the file
+// extension; either way, durations are given as strings, e.g., "30s". The configuration is layered as follows, from the lowest precedence to the highest:
+// 1) The built-in defaults;
+// 2) The file;
+// 3) The environment variables, as described by NewFromEnv.
+//
+// Unlike NewFromEnv, a repository workflow in the file or from the environment variables only needs
+// to list the settings it overrides; the rest are taken from the default workflow.
+//
+// Example:
+// 	github:
+// 	  secrets:
+// 	  - matrix
+// 	  auth_token: keymaker
+// 	  repos:
+// 	  - owner: garukun
+// 	    name: golgtm
+// 	    workflow:
+// 	      approved:
+// 	        triggers:
+// 	        - phrase: lgtm
+// 	          count: 2
+// 	workflow:
+// 	  in_review:
+// 	    label: Needs Review
+func NewFromFile(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Config{}
+	if err := walk("lgtm", reflect.ValueOf(c).Elem(), setDefault); err != nil {
+		return nil, err
+	}
+
+	if filepath.Ext(path) == ".json" {
+		if b, err = jsonToYAML(b); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+
+	if err := yaml.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	if err := walk("lgtm", reflect.ValueOf(c).Elem(), setFromEnv); err != nil {
+		return nil, err
+	}
+
+	// The repository workflows are decoded again on top of the default workflow, now that it is
+	// complete, so that a repository workflow may set any setting, including to a zero value.
+	var file struct {
+		Github struct {
+			Repos []struct {
+				Owner    string      `yaml:"owner"`
+				Name     string      `yaml:"name"`
+				Workflow interface{} `yaml:"workflow"`
+			} `yaml:"repos"`
+		} `yaml:"github"`
+	}
+	if err := yaml.Unmarshal(b, &file); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	workflows := make(map[string]interface{})
+	for _, r := range file.Github.Repos {
+		workflows[strings.ToLower(r.Owner+"/"+r.Name)] = r.Workflow
+	}
+
+	for i, r := range c.Github.Repos {
+		fw := workflows[strings.ToLower(r.FullName())]
+		if fw == nil && !hasEnvPrefix(r.envPrefix()) {
+			c.Github.Repos[i].Workflow = nil
+			continue
+		}
+
+		w := c.Workflow
+		if fw != nil {
+			wb, err := yaml.Marshal(fw)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", r.FullName(), err)
+			}
+
+			if err := yaml.Unmarshal(wb, &w); err != nil {
+				return nil, fmt.Errorf("%s: %v", r.FullName(), err)
+			}
+		}
+
+		if err := walk(r.envPrefix(), reflect.ValueOf(&w).Elem(), setFromEnv); err != nil {
+			return nil, fmt.Errorf("%s: %v", r.FullName(), err)
+		}
+
+		c.Github.Repos[i].Workflow = &w
+	}
+
+	if err := walk("lgtm", reflect.ValueOf(c).Elem(), checkRequired); err != nil {
+		return nil, err
+	}
+
+	return c, c.validate()
+}
+
+// jsonToYAML function converts the JSON document to YAML, so that JSON files are decoded the same
+// way as YAML files; the JSON decoder cannot decode durations from strings.
+func jsonToYAML(b []byte) ([]byte, error) {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(v)
+}
+
+// ParseWorkflow method parses a YAML workflow on top of the base workflow, so that the YAML only
+// needs to list the settings it overrides.
+func ParseWorkflow(b []byte, base *Workflow) (*Workflow, error) {
+	w := *base
+	if err := yaml.Unmarshal(b, &w); err != nil {
+		return nil, err
+	}
+
+	if err := w.validate(); err != nil {
+		return nil, err
+	}
+
+	return &w, nil
+}
+
+// validate method checks the values that the file may set but the environment variables cannot.
+func (c *Config) validate() error {
+	if err := c.checkAuth(); err != nil {
+		return err
+	}
+
+	if err := c.checkSecrets(); err != nil {
+		return err
+	}
+
+	workflows := []*Workflow{&c.Workflow}
+	for _, r := range c.Github.Repos {
+		if len(r.Owner) == 0 || len(r.Name) == 0 {
+			return fmt.Errorf("Invalid repository %s", r.FullName())
+		}
+
+		if r.Workflow != nil {
+			workflows = append(workflows, r.Workflow)
+		}
+	}
+
+	for _, w := range workflows {
+		if err := w.validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *Workflow) validate() error {
+	if err := w.Approved.Teams.validate(); err != nil {
+		return err
+	}
+
+	for _, triggers := range []Triggers{w.InReview.Triggers, w.Approved.Triggers} {
+		for _, t := range triggers {
+			if len(t.Phrase) == 0 || t.Count < 1 {
+				return fmt.Errorf("Invalid trigger %+v", t)
+			}
+		}
+	}
+
+	return nil
+}
+
+// field describes a configuration field the same way as envconfig does.
+type field struct {
+	key   string // Environment variable name, e.g., LGTM_GITHUB_SECRET.
+	tag   reflect.StructTag
+	value reflect.Value
+}
+
+// walk function calls fn for every configuration field of the struct v in depth-first order.
+// Nested structs are walked into unless they decode themselves.
+func walk(prefix string, v reflect.Value, fn func(f field) error) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if len(sf.PkgPath) != 0 {
+			// Unexported field.
+			continue
+		}
+
+		name := sf.Tag.Get("envconfig")
+		if len(name) == 0 {
+			name = sf.Name
+		}
+
+		f := field{
+			key:   strings.ToUpper(prefix + "_" + name),
+			tag:   sf.Tag,
+			value: v.Field(i),
+		}
+
+		if _, ok := f.value.Addr().Interface().(decoder); !ok && f.value.Kind() == reflect.Struct {
+			if err := walk(f.key, f.value, fn); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func setDefault(f field) error {
+	def, ok := f.tag.Lookup("default")
+	if !ok {
+		return nil
+	}
+
+	return decode(f, def)
+}
+
+func setFromEnv(f field) error {
+	value, ok := os.LookupEnv(f.key)
+	if !ok {
+		return nil
+	}
+
+	return decode(f, value)
+}
+
+func checkRequired(f field) error {
+	if f.tag.Get("required") != "true" {
+		return nil
+	}
+
+	if f.value.Len() == 0 {
+		return fmt.Errorf("required key %s missing value", f.key)
+	}
+
+	return nil
+}
+
+// decode function sets the field value from its string representation.
+func decode(f field, value string) error {
+	if d, ok := f.value.Addr().Interface().(decoder); ok {
+		if err := d.Decode(value); err != nil {
+			return fmt.Errorf("%s: %v", f.key, err)
+		}
+
+		return nil
+	}
+
+	switch f.value.Kind() {
+	case reflect.String:
+		f.value.SetString(value)
+	case reflect.Int64:
+		if f.value.Type() != reflect.TypeOf(time.Duration(0)) {
+			return errors.New(f.key + ": unsupported type " + f.value.Type().String())
+		}
+
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%s: %v", f.key, err)
+		}
+
+		f.value.SetInt(int64(d))
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s: %v", f.key, err)
+		}
+
+		f.value.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s: %v", f.key, err)
+		}
+
+		f.value.SetBool(b)
+	case reflect.Slice:
+		if f.value.Type().Elem().Kind() != reflect.String {
+			return errors.New(f.key + ": unsupported type " + f.value.Type().String())
+		}
+
+		f.value.Set(reflect.ValueOf(strings.Split(value, ",")))
+	default:
+		return errors.New(f.key + ": unsupported type " + f.value.Type().String())
+	}
+
+	return nil
+}
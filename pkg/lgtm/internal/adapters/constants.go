@@ -1,7 +1,9 @@
 package adapters
 
 const (
-	GithubEventHeader = "X-GitHub-Event"
-	GithubSigHeader   = "X-Hub-Signature"
-	ResponseHeader    = "X-LGTM-Response"
+	GithubEventHeader    = "X-GitHub-Event"
+	GithubDeliveryHeader = "X-GitHub-Delivery"
+	GithubSigHeader      = "X-Hub-Signature"
+	GithubSig256Header   = "X-Hub-Signature-256"
+	ResponseHeader       = "X-LGTM-Response"
 )
@@ -0,0 +1,75 @@
+package adapters
+
+import (
+	"expvar"
+	"log"
+	"net/http"
+)
+
+// duplicateDeliveries counts the redeliveries ignored.
+var duplicateDeliveries = expvar.NewInt("duplicate_deliveries")
+
+// Deliveries records the IDs of the webhook deliveries handled.
+type Deliveries interface {
+	// Add method records the delivery ID, and returns false if it has been recorded already.
+	Add(id string) (bool, error)
+
+	// Remove method forgets the delivery ID.
+	Remove(id string) error
+}
+
+// Dedupe ignores the redeliveries of the webhook deliveries already handled, e.g., when a delivery
+// that timed out on the side of GitHub is redelivered by hand or through the API. The redeliveries
+// are answered with 200 OK without any side effects.
+//
+// The deliveries failed with a server error are forgotten, so that they are handled again if
+// redelivered. The deliveries without the X-GitHub-Delivery header are always handled.
+type Dedupe struct {
+	Deliveries Deliveries
+}
+
+func (d *Dedupe) Adapt(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		id := req.Header.Get(GithubDeliveryHeader)
+		if len(id) == 0 {
+			h.ServeHTTP(resp, req)
+			return
+		}
+
+		added, err := d.Deliveries.Add(id)
+		if err != nil {
+			// Better handle a redelivery than drop a delivery.
+			log.Printf("cannot record delivery %s: %v", id, err)
+			h.ServeHTTP(resp, req)
+			return
+		}
+
+		if !added {
+			duplicateDeliveries.Add(1)
+			log.Printf("Ignored duplicate delivery %s", id)
+			resp.Header().Set(ResponseHeader, "duplicate delivery")
+			resp.WriteHeader(http.StatusOK)
+			return
+		}
+
+		sw := &statusWriter{ResponseWriter: resp, code: http.StatusOK}
+		h.ServeHTTP(sw, req)
+
+		if sw.code >= http.StatusInternalServerError {
+			if err := d.Deliveries.Remove(id); err != nil {
+				log.Printf("cannot forget failed delivery %s: %v", id, err)
+			}
+		}
+	})
+}
+
+// statusWriter records the status code of the response.
+type statusWriter struct {
+	http.ResponseWriter
+	code int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.code = code
+	w.ResponseWriter.WriteHeader(code)
+}
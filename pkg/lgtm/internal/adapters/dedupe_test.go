@@ -0,0 +1,71 @@
+package adapters_test
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/garukun/golgtm/pkg/lgtm/internal/adapters"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/delivery"
+)
+
+func TestDedupe(t *testing.T) {
+	handled := 0
+	code := http.StatusAccepted
+	h := http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		handled++
+		resp.WriteHeader(code)
+	})
+
+	d := &adapters.Dedupe{Deliveries: &delivery.Cache{TTL: time.Hour}}
+	h = d.Adapt(h).ServeHTTP
+	duplicates := expvar.Get("duplicate_deliveries").(*expvar.Int)
+
+	tests := []struct {
+		delivery   string
+		code       int // The status of the downstream handler.
+		expected   int
+		handled    bool
+		duplicates int64 // The duplicates counted so far.
+	}{
+		{delivery: "a", code: http.StatusAccepted, expected: http.StatusAccepted, handled: true},
+		{delivery: "a", code: http.StatusAccepted, expected: http.StatusOK, duplicates: 1},
+		{delivery: "b", code: http.StatusNoContent, expected: http.StatusNoContent, handled: true, duplicates: 1},
+		{delivery: "a", code: http.StatusAccepted, expected: http.StatusOK, duplicates: 2},
+		// Failed deliveries are handled again.
+		{delivery: "c", code: http.StatusServiceUnavailable, expected: http.StatusServiceUnavailable, handled: true, duplicates: 2},
+		{delivery: "c", code: http.StatusAccepted, expected: http.StatusAccepted, handled: true, duplicates: 2},
+		{delivery: "c", code: http.StatusAccepted, expected: http.StatusOK, duplicates: 3},
+		// Deliveries without an ID are always handled.
+		{code: http.StatusAccepted, expected: http.StatusAccepted, handled: true, duplicates: 3},
+		{code: http.StatusAccepted, expected: http.StatusAccepted, handled: true, duplicates: 3},
+	}
+
+	start := duplicates.Value()
+	for i, test := range tests {
+		t.Logf("Testing %d...", i)
+
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		if len(test.delivery) != 0 {
+			req.Header.Set(adapters.GithubDeliveryHeader, test.delivery)
+		}
+
+		handled, code = 0, test.code
+		resp := httptest.NewRecorder()
+		h.ServeHTTP(resp, req)
+
+		if resp.Code != test.expected {
+			t.Errorf("Expected %d instead of %d.", test.expected, resp.Code)
+		}
+
+		if (handled == 1) != test.handled {
+			t.Errorf("Expected handled %t instead of %d times.", test.handled, handled)
+		}
+
+		if n := duplicates.Value() - start; n != test.duplicates {
+			t.Errorf("Expected %d duplicates instead of %d.", test.duplicates, n)
+		}
+	}
+}
@@ -20,10 +20,11 @@ func (r *EventRouter) Adapt(h http.Handler) http.Handler {
 		a, ok := r.Events[eventType]
 		log.Printf("Event: %s, %t", eventType, ok)
 
+		next := h
 		if ok {
-			h = a.Adapt(h)
+			next = a.Adapt(h)
 		}
 
-		h.ServeHTTP(resp, req)
+		next.ServeHTTP(resp, req)
 	})
 }
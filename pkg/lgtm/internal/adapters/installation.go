@@ -0,0 +1,55 @@
+package adapters
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+)
+
+// Installations records the GitHub App installation of a repository.
+type Installations interface {
+	SetInstallation(owner, repo string, id int)
+}
+
+// Installation records the GitHub App installation of the repository of every webhook event, so
+// that the API requests about the repository are authenticated as the installation.
+type Installation struct {
+	Installations Installations
+}
+
+func (i *Installation) Adapt(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			log.Printf("cannot read request body: %v", err)
+			resp.Header().Set(ResponseHeader, "body")
+			resp.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		// Every event of an App installation has the installation ID; events that are not about a
+		// repository, or that are malformed, are left for the downstream handlers.
+		event := &struct {
+			Installation *struct {
+				ID *int `json:"id"`
+			} `json:"installation"`
+			Repo *struct {
+				Name  *string `json:"name"`
+				Owner *struct {
+					Login *string `json:"login"`
+				} `json:"owner"`
+			} `json:"repository"`
+		}{}
+		if err := json.Unmarshal(body, event); err == nil &&
+			event.Installation != nil && event.Installation.ID != nil &&
+			event.Repo != nil && event.Repo.Name != nil && event.Repo.Owner != nil && event.Repo.Owner.Login != nil {
+			i.Installations.SetInstallation(*event.Repo.Owner.Login, *event.Repo.Name, *event.Installation.ID)
+		}
+
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		h.ServeHTTP(resp, req)
+	})
+}
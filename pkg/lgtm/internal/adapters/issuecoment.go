@@ -13,6 +13,10 @@ import (
 	"github.com/google/go-github/github"
 )
 
+// commentActionCreated is the only issue comment action counted; editing or deleting a comment
+// neither adds nor takes back a vote.
+const commentActionCreated = "created"
+
 // IssueComment handles when a GitHub issue comment event is fired.
 type IssueComment struct {
 	*pr.Updater
@@ -31,14 +35,15 @@ func (c *IssueComment) Adapt(h http.Handler) http.Handler {
 			return
 		}
 
-		if err := c.validate(event); err != nil {
+		id, err := c.validate(event)
+		if err != nil {
 			log.Printf("validate: %v", err)
 			resp.Header().Set(ResponseHeader, err.Error())
 			resp.WriteHeader(http.StatusNoContent)
 			return
 		}
 
-		update, err := c.newUpdate(event)
+		update, phrase, err := c.newUpdate(id, event)
 		if err != nil {
 			log.Printf("issue comment no update: %v", err)
 			resp.Header().Set(ResponseHeader, err.Error())
@@ -49,70 +54,122 @@ func (c *IssueComment) Adapt(h http.Handler) http.Handler {
 		// Enqueue the pending updates.
 		// TODO(@garukun): If we want to report error based on underlying API errors, we'll need to pass
 		// ResponseWriter through the channel.
-		c.Updates() <- *update
+		e := pr.Event{
+			Event:    "issue_comment",
+			Delivery: req.Header.Get(GithubDeliveryHeader),
+			Action:   *event.Action,
+			Actor:    *event.Comment.User.Login,
+			Phrase:   phrase,
+		}
+		if event.Comment.HTMLURL != nil {
+			e.URL = *event.Comment.HTMLURL
+		}
+		if err := c.Enqueue(*update, e); err != nil {
+			log.Printf("cannot enqueue %s: %v", id, err)
+			resp.Header().Set(ResponseHeader, "enqueue")
+			resp.WriteHeader(enqueueStatus(err))
+			return
+		}
 
-		log.Printf("Updated LGTM for %s/%s#%d!", c.Config.Github.Owner, c.Config.Github.Repo, *event.Issue.Number)
+		log.Printf("Updated LGTM for %s!", id)
 		resp.Write([]byte("Done!"))
 
 		// Swallow downstream handlers?
 	})
 }
 
-func (c *IssueComment) validate(e *github.IssueCommentEvent) error {
-	// TODO(@garukun): Should e.Repo.Owner.Login, e.Repo.Name, e.Issue.Number against config
+func (c *IssueComment) validate(e *github.IssueCommentEvent) (pr.ID, error) {
+	if e.Issue == nil || e.Issue.Number == nil || e.Comment == nil {
+		return pr.ID{}, errors.New("no issue comment")
+	}
+
+	id, err := prID(c.Config, e.Repo, *e.Issue.Number)
+	if err != nil {
+		return pr.ID{}, err
+	}
+
+	if e.Action == nil {
+		return pr.ID{}, errors.New("nil comment action")
+	} else if *e.Action != commentActionCreated {
+		return pr.ID{}, fmt.Errorf("invalid action: %s", *e.Action)
+	}
 
 	if e.Issue.PullRequestLinks == nil {
 		// Ignore non-pull requests
-		return errors.New("not pr")
+		return pr.ID{}, errors.New("not pr")
 	}
 
 	if e.Comment.Body == nil || len(strings.TrimSpace(*e.Comment.Body)) == 0 {
 		// Ignore no comments
-		return fmt.Errorf("no comment, %v", e.Comment.Body)
+		return pr.ID{}, fmt.Errorf("no comment, %v", e.Comment.Body)
 	}
 
-	return nil
+	if e.Comment.User == nil || e.Comment.User.Login == nil {
+		// Votes cannot be counted without knowing who commented.
+		return pr.ID{}, errors.New("no commenter")
+	}
+
+	return id, nil
 }
 
-func (c *IssueComment) newUpdate(e *github.IssueCommentEvent) (*pr.Update, error) {
-	update, err := c.checkTriggers(*e.Comment.Body)
+// newUpdate method returns the update of the PR commented on and the trigger phrase said.
+func (c *IssueComment) newUpdate(id pr.ID, e *github.IssueCommentEvent) (*pr.Update, string, error) {
+	w := c.Workflow(id)
+	update, phrase, err := c.checkTriggers(w, id, *e.Comment.User.Login, *e.Comment.Body)
 	if err != nil {
-		return nil, err
+		return nil, phrase, err
 	}
 
 	var label string
 	switch update.State {
 	case pr.InReview:
-		label = c.Config.Workflow.InReview.Label
+		label = w.InReview.Label
 	case pr.Approved:
-		label = c.Config.Workflow.Approved.Label
+		label = w.Approved.Label
 	}
 
-	if !c.shouldUpdateLabels(e.Issue.Labels, label) {
-		return nil, fmt.Errorf("already labeled: %s", label)
+	// Updates with a pull request also update its status, which may have changed regardless.
+	if update.PullRequest == nil && !c.shouldUpdateLabels(e.Issue.Labels, label) {
+		return nil, phrase, fmt.Errorf("already labeled: %s", label)
 	}
 
 	update.Issue = e.Issue
-	update.Number = *e.Issue.Number
-	return update, nil
+	update.ID = id
+	return update, phrase, nil
 }
 
-func (c *IssueComment) checkTriggers(comment string) (*pr.Update, error) {
-	comment = strings.ToLower(strings.TrimSpace(comment))
-	for t := range c.Config.Workflow.Approved.Trigger {
-		if strings.HasPrefix(comment, t) || strings.HasSuffix(comment, t) {
-			return &pr.Update{State: pr.Approved}, nil
+// checkTriggers method matches the comment against the workflow triggers and counts the commenter
+// towards the matched trigger. An update is only returned once the number of distinct commenters
+// who have said the trigger phrase reaches the trigger count; approvals are further subject to the
+// approval requirements of the workflow.
+func (c *IssueComment) checkTriggers(w *config.Workflow, id pr.ID, user, comment string) (*pr.Update, string, error) {
+	if t, ok := w.Approved.Triggers.Match(comment); ok {
+		if _, err := c.Tally(id, t.Phrase, user); err != nil {
+			return nil, t.Phrase, err
 		}
+
+		update, err := c.Evaluate(id)
+		return update, t.Phrase, err
 	}
 
-	for t := range c.Config.Workflow.InReview.Trigger {
-		if strings.HasPrefix(comment, t) || strings.HasSuffix(comment, t) {
-			return &pr.Update{State: pr.InReview}, nil
+	if t, ok := w.InReview.Triggers.Match(comment); ok {
+		n, err := c.Tally(id, t.Phrase, user)
+		if err != nil {
+			return nil, t.Phrase, err
+		} else if n < t.Count {
+			return nil, t.Phrase, fmt.Errorf("%d of %d reviews for %s", n, t.Count, t.Phrase)
+		}
+
+		// Going back to review discards all the approvals so far.
+		if err := c.Reset(id); err != nil {
+			return nil, t.Phrase, err
 		}
+
+		return &pr.Update{State: pr.InReview}, t.Phrase, nil
 	}
 
-	log.Printf("no lgtm triggers: approved:%v, in-review:%v", c.Config.Workflow.Approved.Trigger, c.Config.Workflow.InReview.Trigger)
-	return nil, errors.New("no lgtm triggers")
+	log.Printf("no lgtm triggers: approved:%v, in-review:%v", w.Approved.Triggers, w.InReview.Triggers)
+	return nil, "", errors.New("no lgtm triggers")
 }
 
 func (c *IssueComment) shouldUpdateLabels(labels []github.Label, name string) bool {
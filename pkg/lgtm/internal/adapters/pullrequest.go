@@ -18,6 +18,7 @@ const (
 	prActionLabeled     = "labeled"
 	prActionUnlabeled   = "unlabeled"
 	prActionSynchronize = "synchronize"
+	prActionClosed      = "closed"
 )
 
 type PullRequest struct {
@@ -38,14 +39,29 @@ func (p *PullRequest) Adapt(h http.Handler) http.Handler {
 			return
 		}
 
-		if err := p.validate(event); err != nil {
+		id, err := p.validate(event)
+		if err != nil {
 			log.Printf("validate: %v", err)
 			resp.Header().Set(ResponseHeader, err.Error())
 			resp.WriteHeader(http.StatusNoContent)
 			return
 		}
 
-		update, err := p.newUpdate(event)
+		if *event.Action == prActionClosed {
+			// Closed or merged PRs are not reviewed anymore.
+			if err := p.Forget(id); err != nil {
+				log.Printf("cannot forget %s: %v", id, err)
+				resp.Header().Set(ResponseHeader, "forget")
+				resp.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			log.Printf("Forgot closed %s.", id)
+			resp.Write([]byte("Done!"))
+			return
+		}
+
+		update, err := p.newUpdate(id, event)
 		if err != nil {
 			log.Printf("pr no update: %v", err)
 			resp.Header().Set(ResponseHeader, err.Error())
@@ -56,88 +72,112 @@ func (p *PullRequest) Adapt(h http.Handler) http.Handler {
 		// Enqueue the pending updates.
 		// TODO(@garukun): If we want to report error based on underlying API errors, we'll need to pass
 		// ResponseWriter through the channel.
-		p.Updates() <- *update
+		e := pr.Event{
+			Event:    "pull_request",
+			Action:   *event.Action,
+			Delivery: req.Header.Get(GithubDeliveryHeader),
+		}
+		if event.Sender != nil && event.Sender.Login != nil {
+			e.Actor = *event.Sender.Login
+		}
+		if err := p.Enqueue(*update, e); err != nil {
+			log.Printf("cannot enqueue %s: %v", id, err)
+			resp.Header().Set(ResponseHeader, "enqueue")
+			resp.WriteHeader(enqueueStatus(err))
+			return
+		}
 
-		log.Printf("Updated LGTM for %s/%s#%d!", p.Config.Github.Owner, p.Config.Github.Repo, *event.Number)
+		log.Printf("Updated LGTM for %s!", id)
 		resp.Write([]byte("Done!"))
 
 		// Swallow downstream handlers?
 	})
 }
 
-func (p *PullRequest) validate(e *github.PullRequestEvent) error {
-	// TODO(@garukun): Should e.Repo.Owner.Login, e.Repo.Name, e.Issue.Number against config
+func (p *PullRequest) validate(e *github.PullRequestEvent) (pr.ID, error) {
+	if e.Number == nil {
+		return pr.ID{}, errors.New("nil pr number")
+	}
+
+	id, err := prID(p.Config, e.Repo, *e.Number)
+	if err != nil {
+		return pr.ID{}, err
+	}
 
 	action := e.Action
 	if action == nil {
-		return errors.New("nil pr action")
+		return pr.ID{}, errors.New("nil pr action")
 	}
 
 	switch a := *action; a {
-	case prActionOpened, prActionReopened, prActionLabeled, prActionUnlabeled, prActionSynchronize:
-		return nil
+	case prActionOpened, prActionReopened, prActionLabeled, prActionUnlabeled, prActionSynchronize, prActionClosed:
+		return id, nil
 	default:
-		return fmt.Errorf("invalid action: %s", a)
+		return pr.ID{}, fmt.Errorf("invalid action: %s", a)
 	}
 }
 
-func (p *PullRequest) newUpdate(e *github.PullRequestEvent) (*pr.Update, error) {
+// newUpdate method returns the update of the PR based on its review state. The labels only reflect
+// the review state, so labeled and unlabeled events restore the status of the review state.
+func (p *PullRequest) newUpdate(id pr.ID, e *github.PullRequestEvent) (*pr.Update, error) {
 	var updateIssue *github.Issue
 
+	r, err := p.Record(id)
+	if err != nil {
+		return nil, err
+	}
+
 	switch *e.Action {
 	case prActionSynchronize:
-		issue, err := p.getIssue(*e.Number)
+		// New commits invalidate the approvals so far.
+		if err := p.Reset(id); err != nil {
+			return nil, err
+		}
+
+		issue, err := p.getIssue(id)
 		if err != nil {
 			return nil, err
 		}
 
 		updateIssue = issue
 
-		if !githubLabels(issue.Labels).Contains(p.Config.Workflow.InReview.Label) {
+		if r.State != pr.InReview {
 			// Adding comments in a goroutine is a bit racier because from the moment we verified that it
-			// doesn't contain InReview comments to when the goroutine gets executed, the labels may have
-			// changed.
+			// isn't in review to when the goroutine gets executed, the review state may have changed.
 			go func(p *PullRequest) {
-				log.Printf("revert %s/%s#%d review status", p.Config.Github.Owner, p.Config.Github.Repo, *e.Number)
+				log.Printf("revert %s review status", id)
 
-				if err := p.addComment(*e.Number, "Files changed in PR, revertig code review status."); err != nil {
-					log.Printf("cannot add comment to %s/%s#%d: %v", p.Config.Github.Owner, p.Config.Github.Repo, *e.Number, err)
+				if err := p.addComment(id, "Files changed in PR, revertig code review status."); err != nil {
+					log.Printf("cannot add comment to %s: %v", id, err)
 				}
 			}(p)
 		}
 	case prActionLabeled, prActionUnlabeled:
-		issue, err := p.getIssue(*e.Number)
-		if err != nil {
-			return nil, err
-		}
-
-		if githubLabels(issue.Labels).Contains(p.Config.Workflow.Approved.Label) {
-			return &pr.Update{
-				State:       pr.Approved,
-				Number:      *e.Number,
-				PullRequest: e.PullRequest,
-			}, nil
-		}
+		return &pr.Update{
+			ID:          id,
+			State:       r.State,
+			PullRequest: e.PullRequest,
+		}, nil
 	}
 
 	return &pr.Update{
+		ID:          id,
 		State:       pr.InReview,
-		Number:      *e.Number,
 		Issue:       updateIssue,
 		PullRequest: e.PullRequest,
 	}, nil
 }
 
-func (p *PullRequest) getIssue(number int) (*github.Issue, error) {
-	issue, _, err := p.G.Issues.Get(p.Config.Github.Owner, p.Config.Github.Repo, number)
+func (p *PullRequest) getIssue(id pr.ID) (*github.Issue, error) {
+	issue, _, err := p.G.Issues.Get(id.Owner, id.Repo, id.Number)
 	return issue, err
 }
 
-func (p *PullRequest) addComment(number int, comment string) error {
+func (p *PullRequest) addComment(id pr.ID, comment string) error {
 	ic := &github.IssueComment{
 		Body: &comment,
 	}
 
-	_, _, err := p.G.Issues.CreateComment(p.Config.Github.Owner, p.Config.Github.Repo, number, ic)
+	_, _, err := p.G.Issues.CreateComment(id.Owner, id.Repo, id.Number, ic)
 	return err
 }
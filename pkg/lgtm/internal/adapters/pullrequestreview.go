@@ -0,0 +1,177 @@
+package adapters
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/garukun/golgtm/pkg/lgtm/config"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/pr"
+	"github.com/google/go-github/github"
+)
+
+const (
+	reviewActionSubmitted = "submitted"
+	reviewActionDismissed = "dismissed"
+
+	reviewStateApproved         = "approved"
+	reviewStateChangesRequested = "changes_requested"
+)
+
+// PullRequestReview handles when a GitHub pull request review event is fired. Approving reviews
+// count as approvals alongside the approved triggers, requesting changes sends the PR back to
+// review and dismissing an approving review revokes the approval.
+type PullRequestReview struct {
+	*pr.Updater
+
+	G      *github.Client
+	Config *config.Config
+}
+
+func (p *PullRequestReview) Adapt(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		event := &github.PullRequestReviewEvent{}
+
+		if err := json.NewDecoder(req.Body).Decode(event); err != nil {
+			log.Print("unmarshal: ", err)
+			resp.Header().Set(ResponseHeader, "pr review fmt")
+			resp.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		id, err := p.validate(event)
+		if err != nil {
+			log.Printf("validate: %v", err)
+			resp.Header().Set(ResponseHeader, err.Error())
+			resp.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		update, err := p.newUpdate(id, event)
+		if err != nil {
+			log.Printf("pr review no update: %v", err)
+			resp.Header().Set(ResponseHeader, err.Error())
+			resp.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		e := pr.Event{
+			Event:    "pull_request_review",
+			Action:   *event.Action,
+			Delivery: req.Header.Get(GithubDeliveryHeader),
+			Actor:    *event.Review.User.Login,
+			Phrase:   reviewState(event.Review),
+		}
+		if event.Review.HTMLURL != nil {
+			e.URL = *event.Review.HTMLURL
+		}
+		if err := p.Enqueue(*update, e); err != nil {
+			log.Printf("cannot enqueue %s: %v", id, err)
+			resp.Header().Set(ResponseHeader, "enqueue")
+			resp.WriteHeader(enqueueStatus(err))
+			return
+		}
+
+		log.Printf("Updated LGTM for %s!", id)
+		resp.Write([]byte("Done!"))
+	})
+}
+
+func (p *PullRequestReview) validate(e *github.PullRequestReviewEvent) (pr.ID, error) {
+	if e.PullRequest == nil || e.PullRequest.Number == nil {
+		return pr.ID{}, errors.New("nil pr number")
+	}
+
+	id, err := prID(p.Config, e.Repo, *e.PullRequest.Number)
+	if err != nil {
+		return pr.ID{}, err
+	}
+
+	if e.Action == nil {
+		return pr.ID{}, errors.New("nil pr review action")
+	}
+
+	if e.Review == nil || e.Review.User == nil || e.Review.User.Login == nil {
+		// Approvals cannot be counted without knowing who reviewed.
+		return pr.ID{}, errors.New("no reviewer")
+	}
+
+	state := reviewState(e.Review)
+	switch a := *e.Action; {
+	case a == reviewActionSubmitted && (state == reviewStateApproved || state == reviewStateChangesRequested):
+		return id, nil
+	case a == reviewActionDismissed:
+		return id, nil
+	default:
+		return pr.ID{}, fmt.Errorf("invalid review: %s %s", a, state)
+	}
+}
+
+// newUpdate method returns the update of the PR based on the review.
+func (p *PullRequestReview) newUpdate(id pr.ID, e *github.PullRequestReviewEvent) (*pr.Update, error) {
+	reviewer := *e.Review.User.Login
+
+	var update *pr.Update
+	switch {
+	case *e.Action == reviewActionDismissed:
+		if err := p.Revoke(id, pr.ReviewApproval, reviewer); err != nil {
+			return nil, err
+		}
+
+		up, err := p.Evaluate(id)
+		if _, ok := err.(*pr.NotApprovedError); ok {
+			// Without the dismissed approval, the PR needs more review.
+			up, err = &pr.Update{State: pr.InReview}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		update = up
+	case reviewState(e.Review) == reviewStateApproved:
+		if _, err := p.Tally(id, pr.ReviewApproval, reviewer); err != nil {
+			return nil, err
+		}
+
+		up, err := p.Evaluate(id)
+		if err != nil {
+			return nil, err
+		}
+
+		update = up
+	default:
+		// Requesting changes is the same as saying an in review trigger; all the approvals so far are
+		// discarded.
+		if err := p.Reset(id); err != nil {
+			return nil, err
+		}
+
+		update = &pr.Update{State: pr.InReview}
+	}
+
+	issue, _, err := p.G.Issues.Get(id.Owner, id.Repo, id.Number)
+	if err != nil {
+		return nil, err
+	}
+
+	update.ID = id
+	update.Issue = issue
+	if update.PullRequest == nil {
+		update.PullRequest = e.PullRequest
+	}
+
+	return update, nil
+}
+
+// reviewState function returns the lower case state of the review; webhook payloads and the API use
+// different cases.
+func reviewState(r *github.PullRequestReview) string {
+	if r == nil || r.State == nil {
+		return ""
+	}
+
+	return strings.ToLower(*r.State)
+}
@@ -0,0 +1,69 @@
+package adapters
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/garukun/golgtm/pkg/lgtm/config"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/repoconfig"
+	"github.com/google/go-github/github"
+)
+
+// Push handles when a GitHub push event is fired and refreshes the workflow that a repository
+// configures for itself whenever its default branch changes.
+type Push struct {
+	Workflows *repoconfig.Cache
+	Config    *config.Config
+}
+
+func (p *Push) Adapt(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		event := &github.PushEvent{}
+
+		if err := json.NewDecoder(req.Body).Decode(event); err != nil {
+			log.Print("unmarshal: ", err)
+			resp.Header().Set(ResponseHeader, "push fmt")
+			resp.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		owner, repo, err := p.validate(event)
+		if err != nil {
+			log.Printf("validate: %v", err)
+			resp.Header().Set(ResponseHeader, err.Error())
+			resp.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if _, err := p.Workflows.Refresh(owner, repo); err != nil {
+			log.Printf("cannot refresh %s of %s/%s: %v", repoconfig.File, owner, repo, err)
+			resp.Header().Set(ResponseHeader, "refresh")
+			resp.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		log.Printf("Refreshed %s of %s/%s!", repoconfig.File, owner, repo)
+		resp.Write([]byte("Done!"))
+	})
+}
+
+func (p *Push) validate(e *github.PushEvent) (string, string, error) {
+	r := e.Repo
+	if r == nil || r.Owner == nil || r.Owner.Name == nil || r.Name == nil {
+		return "", "", errors.New("no repo")
+	}
+
+	owner, repo := *r.Owner.Name, *r.Name
+	if _, ok := p.Config.Repo(owner, repo); !ok {
+		return "", "", fmt.Errorf("repo not allowed: %s/%s", owner, repo)
+	}
+
+	if e.Ref == nil || r.DefaultBranch == nil || *e.Ref != "refs/heads/"+*r.DefaultBranch {
+		return "", "", errors.New("not default branch")
+	}
+
+	return owner, repo, nil
+}
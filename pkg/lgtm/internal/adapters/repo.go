@@ -0,0 +1,38 @@
+package adapters
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/garukun/golgtm/pkg/lgtm/config"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/pr"
+	"github.com/google/go-github/github"
+)
+
+// prID function returns the ID of the PR numbered in the given event repository, or an error if
+// the repository is not one of the configured repositories.
+func prID(conf *config.Config, r *github.Repository, number int) (pr.ID, error) {
+	if r == nil || r.Owner == nil || r.Owner.Login == nil || r.Name == nil {
+		return pr.ID{}, errors.New("no repo")
+	}
+
+	owner, name := *r.Owner.Login, *r.Name
+	if _, ok := conf.Repo(owner, name); !ok {
+		return pr.ID{}, fmt.Errorf("repo not allowed: %s/%s", owner, name)
+	}
+
+	return pr.ID{Owner: owner, Repo: name, Number: number}, nil
+}
+
+// enqueueStatus function returns the HTTP status code of the response to a webhook whose update
+// cannot be enqueued. GitHub does not redeliver failed webhooks by itself, so the status only shows
+// the failure in the recent deliveries of the webhook; an update refused by a full queue is kept as
+// a dead letter to replay.
+func enqueueStatus(err error) int {
+	if err == pr.ErrClosed || err == pr.ErrQueueFull {
+		return http.StatusServiceUnavailable
+	}
+
+	return http.StatusInternalServerError
+}
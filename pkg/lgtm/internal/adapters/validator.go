@@ -4,29 +4,80 @@ import (
 	"bytes"
 	"crypto/hmac"
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"io"
+	"hash"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"strings"
 )
 
+// Validator validates the GitHub signature of every request; see
+// https://developer.github.com/webhooks/securing/.
+//
+// The HMAC-SHA256 signature of the X-Hub-Signature-256 header is preferred. The HMAC-SHA1 signature
+// of the X-Hub-Signature header is only validated in absence of the former and if allowed.
 type Validator struct {
-	// Secret against the Github signature on every request.
-	Secret []byte
+	// Secrets against the GitHub signature on every request. A request signed with any of them is
+	// valid, so that the webhook secret can be rotated without downtime.
+	Secrets [][]byte
+
+	// AllowSHA1 allows requests with only the HMAC-SHA1 signature.
+	AllowSHA1 bool
 }
 
-func (v *Validator) validate(body io.Reader, signature string) error {
-	mac := hmac.New(sha1.New, v.Secret)
-	io.Copy(mac, body)
+func (v *Validator) validate(body []byte, header http.Header) error {
+	algo, signature, err := v.signature(header)
+	if err != nil {
+		return err
+	}
+
+	for _, secret := range v.Secrets {
+		mac := hmac.New(algo, secret)
+		mac.Write(body)
 
-	if sig := hex.EncodeToString(mac.Sum(nil)); sig != signature {
-		return fmt.Errorf("Invalid request signature: %s, expecting %s", signature, sig)
+		if hmac.Equal(mac.Sum(nil), signature) {
+			return nil
+		}
 	}
 
-	return nil
+	return fmt.Errorf("Invalid request signature: %x", signature)
+}
+
+// signature method returns the hash algorithm and the decoded signature of the request.
+func (v *Validator) signature(header http.Header) (func() hash.Hash, []byte, error) {
+	if sig := header.Get(GithubSig256Header); len(sig) != 0 {
+		return parseSignature(sha256.New, "sha256=", sig)
+	}
+
+	if sig := header.Get(GithubSigHeader); len(sig) != 0 {
+		if !v.AllowSHA1 {
+			return nil, nil, errors.New("SHA1 signature not allowed")
+		}
+
+		return parseSignature(sha1.New, "sha1=", sig)
+	}
+
+	return nil, nil, errors.New("Missing request signature")
+}
+
+// parseSignature function decodes the hex signature after the prefix indicating the hash algorithm,
+// e.g. "sha256=".
+func parseSignature(algo func() hash.Hash, prefix, sig string) (func() hash.Hash, []byte, error) {
+	if !strings.HasPrefix(sig, prefix) {
+		return nil, nil, fmt.Errorf("Malformed request signature: %q", sig)
+	}
+
+	b, err := hex.DecodeString(sig[len(prefix):])
+	if err != nil || len(b) != algo().Size() {
+		return nil, nil, fmt.Errorf("Malformed request signature: %q", sig)
+	}
+
+	return algo, b, nil
 }
 
 func (v *Validator) Adapt(h http.Handler) http.Handler {
@@ -38,21 +89,23 @@ func (v *Validator) Adapt(h http.Handler) http.Handler {
 			return
 		}
 
-		signature := req.Header.Get(GithubSigHeader)
-		downstream := bytes.NewBuffer(nil)
-		payload := io.TeeReader(req.Body, downstream)
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			log.Printf("cannot read request body: %v", err)
+			resp.Header().Set(ResponseHeader, "body")
+			resp.WriteHeader(http.StatusBadRequest)
+			return
+		}
 
-		// Skip the first 5 characters because it's used to indicate the hash mechanism, e.g. "sha1:".
-		signature = signature[5:]
-		if err := v.validate(payload, signature); err != nil {
+		if err := v.validate(body, req.Header); err != nil {
 			log.Print(err)
-			log.Printf("HTTP request body: %s", base64.StdEncoding.EncodeToString(downstream.Bytes()))
+			log.Printf("HTTP request body: %s", base64.StdEncoding.EncodeToString(body))
 			resp.Header().Set(ResponseHeader, "naughty hacker")
 			resp.WriteHeader(http.StatusBadRequest)
 			return
 		}
 
-		req.Body = ioutil.NopCloser(downstream)
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
 
 		h.ServeHTTP(resp, req)
 	})
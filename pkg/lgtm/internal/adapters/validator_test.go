@@ -13,26 +13,80 @@ import (
 func TestValidateSignature(t *testing.T) {
 	const customSuccessStatus = 529 // Some unique non-standard HTTP status code
 
+	const (
+		sha1Sig   = "sha1=041711d156ab84e80e9ef409de159d64b6a7b04d"                           // SHA1 of "There is no spoon".
+		sha256Sig = "sha256=2d244a4884425334780cbd48a4f731fc92a0e189eccae52c27d72edd48d4ae13" // SHA256 of "There is no spoon".
+	)
+
 	tests := []struct {
 		body      string
-		secret    string
-		signature string
+		secrets   []string
+		allowSHA1 bool
+		header    map[string]string
 		status    int
 	}{
 		// Successful validation
+		{
+			body:    "There is no spoon",
+			secrets: []string{"matrix"},
+			header:  map[string]string{adapters.GithubSig256Header: sha256Sig, adapters.GithubSigHeader: sha1Sig},
+			status:  customSuccessStatus,
+		},
+		// Any secret being rotated
+		{
+			body:    "There is no spoon",
+			secrets: []string{"zion", "matrix"},
+			header:  map[string]string{adapters.GithubSig256Header: sha256Sig},
+			status:  customSuccessStatus,
+		},
+		// Wrong secret
+		{
+			body:    "There is no spoon",
+			secrets: []string{"MATRIX"},
+			header:  map[string]string{adapters.GithubSig256Header: sha256Sig},
+			status:  http.StatusBadRequest,
+		},
+		// Allowed SHA1 fallback
 		{
 			body:      "There is no spoon",
-			secret:    "matrix",
-			signature: "sha1:041711d156ab84e80e9ef409de159d64b6a7b04d", // SHA1 of "There is no spoon".
+			secrets:   []string{"matrix"},
+			allowSHA1: true,
+			header:    map[string]string{adapters.GithubSigHeader: sha1Sig},
 			status:    customSuccessStatus,
 		},
-		// Wrong secret
+		// SHA1 not allowed
+		{
+			body:    "There is no spoon",
+			secrets: []string{"matrix"},
+			header:  map[string]string{adapters.GithubSigHeader: sha1Sig},
+			status:  http.StatusBadRequest,
+		},
+		// Missing signature
 		{
 			body:      "There is no spoon",
-			secret:    "MATRIX",
-			signature: "sha1:041711d156ab84e80e9ef409de159d64b6a7b04d", // SHA1 of "There is no spoon".
+			secrets:   []string{"matrix"},
+			allowSHA1: true,
 			status:    http.StatusBadRequest,
 		},
+		// Malformed signatures
+		{
+			body:    "There is no spoon",
+			secrets: []string{"matrix"},
+			header:  map[string]string{adapters.GithubSig256Header: "sha"},
+			status:  http.StatusBadRequest,
+		},
+		{
+			body:    "There is no spoon",
+			secrets: []string{"matrix"},
+			header:  map[string]string{adapters.GithubSig256Header: "sha256=2d244a"},
+			status:  http.StatusBadRequest,
+		},
+		{
+			body:    "There is no spoon",
+			secrets: []string{"matrix"},
+			header:  map[string]string{adapters.GithubSig256Header: sha1Sig},
+			status:  http.StatusBadRequest,
+		},
 	}
 
 	// Test http.Handler implementation to verify the actual HTTP handling.
@@ -54,10 +108,16 @@ func TestValidateSignature(t *testing.T) {
 		bodyReader := bytes.NewReader([]byte(test.body))
 
 		req := httptest.NewRequest(http.MethodPost, "http://localhost", bodyReader)
-		req.Header.Set(adapters.GithubSigHeader, test.signature)
+		for k, v := range test.header {
+			req.Header.Set(k, v)
+		}
 		resp := httptest.NewRecorder()
 
-		v := &adapters.Validator{Secret: []byte(test.secret)}
+		v := &adapters.Validator{AllowSHA1: test.allowSHA1}
+		for _, secret := range test.secrets {
+			v.Secrets = append(v.Secrets, []byte(secret))
+		}
+
 		h := v.Adapt(testHandler(test.body))
 		h.ServeHTTP(resp, req)
 
@@ -0,0 +1,298 @@
+/*
+Package admin provides the administrative HTTP API of LGTM.
+
+Routes:
+	GET  /deadletters                              lists the updates that could not be applied;
+	POST /deadletters/replay?id=N                  evaluates the PR of dead letter N again;
+	GET  /prs/OWNER/REPO/N                         shows the recorded and the evaluated state of PR N;
+	POST /prs/OWNER/REPO/N/state?state=S&reason=R  forces PR N into state S, in_review or approved;
+	POST /prs/OWNER/REPO/N/clear?reason=R          forgets the approvals of PR N, putting it in review;
+	POST /prs/OWNER/REPO/N/evaluate                evaluates the approvals of PR N again;
+	GET  /prs/OWNER/REPO/N/audit                   lists the review decisions on PR N, if audited.
+
+Requests must carry the configured admin token as a bearer token in the Authorization header. The
+replays and the routes under /prs are refused unless the admin token is configured, since they
+change the review state of PRs; every change is recorded in the history of the PR with the given
+reason.
+*/
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/garukun/golgtm/pkg/lgtm/internal/pr"
+)
+
+// actor is who the changes made through the API are attributed to.
+const actor = "admin"
+
+// New function returns the http.Handler of the administrative API.
+func New(u *pr.Updater) http.Handler {
+	a := &api{u: u}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/deadletters", a.deadLetters)
+	mux.HandleFunc("/deadletters/replay", a.authorized(a.replay))
+	mux.HandleFunc("/prs/", a.authorized(a.pr))
+	return a.authenticate(mux)
+}
+
+type api struct {
+	u *pr.Updater
+}
+
+func (a *api) deadLetters(resp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		resp.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeJSON(resp, a.u.DeadLetters())
+}
+
+func (a *api) replay(resp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		resp.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.ParseUint(req.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(resp, "invalid dead letter id", http.StatusBadRequest)
+		return
+	}
+
+	if err := a.u.Replay(id, actor); err == pr.ErrNoDeadLetter {
+		http.Error(resp, err.Error(), http.StatusNotFound)
+		return
+	} else if err == pr.ErrQueueFull {
+		http.Error(resp, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
+		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Replayed dead letter %d", id)
+	resp.WriteHeader(http.StatusAccepted)
+}
+
+// authenticate method rejects the requests without the admin token, if configured.
+func (a *api) authenticate(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		token := a.u.Config.AdminToken
+		if len(token) == 0 {
+			h.ServeHTTP(resp, req)
+			return
+		}
+
+		auth := req.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") ||
+			subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(token)) != 1 {
+			resp.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(resp, "invalid admin token", http.StatusUnauthorized)
+			return
+		}
+
+		h.ServeHTTP(resp, req)
+	})
+}
+
+// authorized method refuses the requests unless the admin token is configured.
+func (a *api) authorized(fn http.HandlerFunc) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		if len(a.u.Config.AdminToken) == 0 {
+			http.Error(resp, "admin token not configured", http.StatusForbidden)
+			return
+		}
+
+		fn(resp, req)
+	}
+}
+
+// prView is the review state of a PR.
+type prView struct {
+	Owner      string
+	Repo       string
+	Number     int
+	State      string
+	HeadSHA    string
+	Since      time.Time
+	Votes      map[string][]string
+	History    []eventView
+	Evaluation evaluation
+}
+
+type eventView struct {
+	pr.Event
+	State string
+}
+
+// evaluation is the outcome of evaluating the votes on a PR.
+type evaluation struct {
+	State       string
+	Description string   `json:",omitempty"`
+	Counts      []string `json:",omitempty"` // The vote counts, if not approved.
+	Uncovered   []string `json:",omitempty"` // The changed files no required OWNERS file covers.
+	Error       string   `json:",omitempty"`
+}
+
+var states = map[string]pr.State{
+	"in_review": pr.InReview,
+	"approved":  pr.Approved,
+}
+
+func (a *api) pr(resp http.ResponseWriter, req *http.Request) {
+	// OWNER/REPO/N[/ACTION]
+	parts := strings.Split(strings.TrimPrefix(req.URL.Path, "/prs/"), "/")
+	if len(parts) < 3 || len(parts) > 4 {
+		http.NotFound(resp, req)
+		return
+	}
+
+	number, err := strconv.Atoi(parts[2])
+	if err != nil {
+		http.Error(resp, "invalid PR number", http.StatusBadRequest)
+		return
+	}
+
+	repo, ok := a.u.Config.Repo(parts[0], parts[1])
+	if !ok {
+		http.Error(resp, "repository not configured", http.StatusNotFound)
+		return
+	}
+
+	id := pr.ID{Owner: repo.Owner, Repo: repo.Name, Number: number}
+	if len(parts) == 3 {
+		if req.Method != http.MethodGet {
+			resp.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		a.view(resp, id)
+		return
+	}
+
+	if parts[3] == "audit" {
+		if req.Method != http.MethodGet {
+			resp.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		a.audit(resp, id)
+		return
+	}
+
+	if req.Method != http.MethodPost {
+		resp.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := req.URL.Query()
+	reason := q.Get("reason")
+
+	switch parts[3] {
+	case "state":
+		state, ok := states[q.Get("state")]
+		if !ok {
+			http.Error(resp, "invalid state, expecting in_review or approved", http.StatusBadRequest)
+			return
+		}
+
+		if len(reason) == 0 {
+			http.Error(resp, "missing reason", http.StatusBadRequest)
+			return
+		}
+
+		err = a.u.Override(id, state, actor, reason)
+	case "clear":
+		if len(reason) == 0 {
+			http.Error(resp, "missing reason", http.StatusBadRequest)
+			return
+		}
+
+		err = a.u.Clear(id, actor, reason)
+	case "evaluate":
+		err = a.u.Reevaluate(id, actor)
+	default:
+		http.NotFound(resp, req)
+		return
+	}
+
+	if err == pr.ErrQueueFull || err == pr.ErrClosed {
+		http.Error(resp, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
+		log.Printf("cannot %s %s: %v", parts[3], id, err)
+		http.Error(resp, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	resp.WriteHeader(http.StatusAccepted)
+}
+
+func (a *api) view(resp http.ResponseWriter, id pr.ID) {
+	r, err := a.u.Record(id)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	v := prView{
+		Owner:   id.Owner,
+		Repo:    id.Repo,
+		Number:  id.Number,
+		State:   r.State.String(),
+		HeadSHA: r.HeadSHA,
+		Since:   r.Since,
+		Votes:   r.Votes,
+	}
+
+	for _, e := range r.History {
+		v.History = append(v.History, eventView{Event: e, State: e.State.String()})
+	}
+
+	update, err := a.u.Evaluate(id)
+	switch err := err.(type) {
+	case nil:
+		v.Evaluation = evaluation{State: update.State.String(), Description: update.Description}
+	case *pr.NotApprovedError:
+		v.Evaluation = evaluation{State: pr.InReview.String(), Counts: err.Counts, Uncovered: err.Uncovered}
+	default:
+		v.Evaluation = evaluation{Error: err.Error()}
+	}
+
+	writeJSON(resp, v)
+}
+
+func (a *api) audit(resp http.ResponseWriter, id pr.ID) {
+	if a.u.Audit == nil {
+		http.Error(resp, "audit log not configured", http.StatusNotFound)
+		return
+	}
+
+	entries, err := a.u.Audit.Query(id)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if entries == nil {
+		entries = []pr.AuditEntry{}
+	}
+
+	writeJSON(resp, entries)
+}
+
+func writeJSON(resp http.ResponseWriter, v interface{}) {
+	resp.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(resp).Encode(v); err != nil {
+		log.Printf("cannot encode response: %v", err)
+	}
+}
@@ -0,0 +1,196 @@
+package admin_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/garukun/golgtm/pkg/lgtm/config"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/admin"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/githubtest"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/pr"
+	"github.com/google/go-github/github"
+)
+
+func TestAuth(t *testing.T) {
+	tests := []struct {
+		token  string // The configured admin token.
+		header string
+		method string // GET if empty.
+		path   string
+		code   int
+	}{
+		{path: "/deadletters", code: http.StatusOK},
+		{method: http.MethodPost, path: "/deadletters/replay?id=1", code: http.StatusForbidden},
+		{path: "/prs/garukun/golgtm/1", code: http.StatusForbidden},
+		{token: "secret", path: "/deadletters", code: http.StatusUnauthorized},
+		{token: "secret", header: "Bearer wrong", path: "/prs/garukun/golgtm/1", code: http.StatusUnauthorized},
+		{token: "secret", header: "secret", path: "/prs/garukun/golgtm/1", code: http.StatusUnauthorized},
+		{token: "secret", header: "Bearer secret", path: "/deadletters", code: http.StatusOK},
+		{token: "secret", header: "Bearer secret", method: http.MethodPost, path: "/deadletters/replay?id=1", code: http.StatusNotFound},
+		{token: "secret", header: "Bearer secret", path: "/prs/garukun/golgtm/1", code: http.StatusOK},
+		{token: "secret", header: "Bearer secret", path: "/prs/garukun/other/1", code: http.StatusNotFound},
+		{token: "secret", header: "Bearer secret", path: "/prs/garukun/golgtm/1/audit", code: http.StatusNotFound},
+	}
+
+	for i, test := range tests {
+		t.Logf("Testing %d...", i)
+
+		conf := &config.Config{AdminToken: test.token}
+		conf.Github.Repos = []config.Repo{{Owner: "garukun", Name: "golgtm"}}
+		conf.Workflow.Approved.Triggers = config.Triggers{{Phrase: "lgtm", Count: 1}}
+
+		h := admin.New(&pr.Updater{Logger: log.New(ioutil.Discard, "", 0), Config: conf})
+
+		method := test.method
+		if len(method) == 0 {
+			method = http.MethodGet
+		}
+
+		req := httptest.NewRequest(method, test.path, nil)
+		if len(test.header) != 0 {
+			req.Header.Set("Authorization", test.header)
+		}
+
+		resp := httptest.NewRecorder()
+		h.ServeHTTP(resp, req)
+		if resp.Code != test.code {
+			t.Errorf("Expected %d instead of %d.", test.code, resp.Code)
+		}
+	}
+}
+
+// event is an event as shown by the API.
+type event struct {
+	Event   string
+	Action  string
+	Actor   string
+	Reason  string
+	State   string
+	HeadSHA string
+}
+
+func TestPR(t *testing.T) {
+	voted := map[string][]string{"lgtm": {"trinity"}}
+
+	tests := []struct {
+		path   string
+		code   int
+		labels []string // The labels of the PR after the request.
+		state  string   // The review state of the PR after the request.
+		votes  map[string][]string
+		event  event // The last event recorded.
+	}{
+		{
+			path:   "/prs/garukun/golgtm/1/state?state=approved&reason=hotfix",
+			code:   http.StatusAccepted,
+			labels: []string{"Ready"},
+			state:  "approved",
+			votes:  voted,
+			event:  event{Event: "admin", Action: "force", Actor: "admin", Reason: "hotfix", State: "approved", HeadSHA: "head"},
+		},
+		{path: "/prs/garukun/golgtm/1/state?state=approved", code: http.StatusBadRequest},
+		{path: "/prs/garukun/golgtm/1/state?state=merged&reason=hotfix", code: http.StatusBadRequest},
+		{path: "/prs/garukun/golgtm/1/clear", code: http.StatusBadRequest},
+		{
+			path:   "/prs/garukun/golgtm/1/clear?reason=bogus",
+			code:   http.StatusAccepted,
+			labels: []string{"Needs Review"},
+			state:  "in review",
+			event:  event{Event: "admin", Action: "clear", Actor: "admin", Reason: "bogus", State: "in review", HeadSHA: "head"},
+		},
+		{
+			path:   "/prs/garukun/golgtm/1/evaluate",
+			code:   http.StatusAccepted,
+			labels: []string{"Ready"},
+			state:  "approved",
+			votes:  voted,
+			event:  event{Event: "admin", Action: "evaluate", Actor: "admin", State: "approved", HeadSHA: "head"},
+		},
+		{path: "/prs/garukun/golgtm/1/merge", code: http.StatusNotFound},
+		{path: "/prs/garukun/golgtm/x/evaluate", code: http.StatusBadRequest},
+	}
+
+	for i, test := range tests {
+		t.Logf("Testing %d...", i)
+
+		s := githubtest.NewServer()
+		s.AddPull("garukun", "golgtm", githubtest.Pull{Number: 1, Author: "neo", HeadSHA: "head", Labels: []string{"Needs Review"}})
+
+		g := github.NewClient(nil)
+		g.BaseURL, _ = url.Parse(s.BaseURL())
+
+		conf := &config.Config{AdminToken: "secret"}
+		conf.Github.Repos = []config.Repo{{Owner: "garukun", Name: "golgtm"}}
+		conf.Workflow.InReview.Label = "Needs Review"
+		conf.Workflow.Approved.Label = "Ready"
+		conf.Workflow.Approved.Triggers = config.Triggers{{Phrase: "lgtm", Count: 1}}
+
+		u := &pr.Updater{Logger: log.New(ioutil.Discard, "", 0), G: g, Config: conf}
+		u.Start()
+
+		id := pr.ID{Owner: "garukun", Repo: "golgtm", Number: 1}
+		u.Tally(id, "lgtm", "trinity")
+
+		req := httptest.NewRequest(http.MethodPost, test.path, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+
+		resp := httptest.NewRecorder()
+		admin.New(u).ServeHTTP(resp, req)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		u.Close(ctx)
+		cancel()
+		s.Close()
+
+		if resp.Code != test.code {
+			t.Errorf("Expected %d instead of %d.", test.code, resp.Code)
+			continue
+		}
+
+		if resp.Code != http.StatusAccepted {
+			continue
+		}
+
+		if labels := s.Labels("garukun", "golgtm", 1); !reflect.DeepEqual(labels, test.labels) {
+			t.Errorf("Expected labels %v instead of %v.", test.labels, labels)
+		}
+
+		req = httptest.NewRequest(http.MethodGet, "/prs/garukun/golgtm/1", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+
+		resp = httptest.NewRecorder()
+		admin.New(u).ServeHTTP(resp, req)
+
+		var v struct {
+			State   string
+			Votes   map[string][]string
+			History []event
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+			t.Errorf("Unexpected error %v.", err)
+			continue
+		}
+
+		if v.State != test.state {
+			t.Errorf("Expected state %q instead of %q.", test.state, v.State)
+		}
+
+		if !reflect.DeepEqual(v.Votes, test.votes) {
+			t.Errorf("Expected votes %v instead of %v.", test.votes, v.Votes)
+		}
+
+		if n := len(v.History); n == 0 {
+			t.Errorf("Expected event %+v instead of none.", test.event)
+		} else if e := v.History[n-1]; e != test.event {
+			t.Errorf("Expected event %+v instead of %+v.", test.event, e)
+		}
+	}
+}
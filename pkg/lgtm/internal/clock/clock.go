@@ -0,0 +1,25 @@
+/*
+Package clock tells the time to the background jobs, so that tests may inject a fake clock.
+*/
+package clock
+
+import "time"
+
+// Clock tells the time and waits for it to pass.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// System is the system clock.
+var System Clock = system{}
+
+type system struct{}
+
+func (system) Now() time.Time {
+	return time.Now()
+}
+
+func (system) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
@@ -0,0 +1,50 @@
+/*
+Package clocktest provides a fake clock for tests, whose time only moves when the test says so.
+*/
+package clocktest
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a fake clock.Clock. Its Now is the time last set, and its After returns Ticks, so that
+// the test sends the ticks itself; a nil Ticks never ticks.
+type Clock struct {
+	Ticks chan time.Time
+
+	mu  sync.Mutex
+	now time.Time
+}
+
+// New function returns a Clock at the given time.
+func New(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// Set method moves the Clock to the given time.
+func (c *Clock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = now
+}
+
+// Add method moves the Clock by the given duration.
+func (c *Clock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	return c.Ticks
+}
@@ -0,0 +1,275 @@
+/*
+Package codeowners resolves GitHub CODEOWNERS files, which list the owners of the files of a
+repository by gitignore style patterns.
+
+Every line of a CODEOWNERS file is a pattern followed by its owners: users (@user), teams
+(@org/team) or email addresses. The last pattern matching a file owns it; a pattern without owners
+leaves its files without owners. As on GitHub, negated patterns, character ranges and escaped
+leading hashes are not supported, and invalid lines are skipped rather than failing the file.
+
+Email owners cannot be mapped to users without their verified emails, so they are ignored; a line of
+email owners only is skipped altogether.
+*/
+package codeowners
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/garukun/golgtm/pkg/lgtm/internal/owners"
+	"github.com/google/go-github/github"
+)
+
+// Paths are the paths where the CODEOWNERS file is looked up, in order; the first one found is used.
+var Paths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// Rule is a line of a CODEOWNERS file.
+type Rule struct {
+	Pattern string
+
+	// Owners are the lower case owners without the leading @, e.g., neo or garukun/platform-team.
+	Owners []string
+
+	re *regexp.Regexp
+}
+
+// Match method returns whether the pattern of the rule matches the file path.
+func (r Rule) Match(name string) bool {
+	return r.re.MatchString(strings.TrimPrefix(name, "/"))
+}
+
+// File is the content of a CODEOWNERS file.
+type File struct {
+	Rules []Rule
+
+	// Skipped are the reasons the lines not parsed into rules were skipped for.
+	Skipped []string
+}
+
+// Parse function parses the content of a CODEOWNERS file.
+func Parse(b []byte) (*File, error) {
+	f := &File{}
+
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; s.Scan(); n++ {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		r, err := parseRule(fields)
+		if err != nil {
+			f.Skipped = append(f.Skipped, fmt.Sprintf("line %d: %v", n, err))
+			continue
+		}
+
+		f.Rules = append(f.Rules, r)
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// parseRule function parses the fields of a line into a rule, ignoring the email owners.
+func parseRule(fields []string) (Rule, error) {
+	r := Rule{Pattern: fields[0], re: compile(fields[0])}
+
+	emails := 0
+	for _, owner := range fields[1:] {
+		if strings.HasPrefix(owner, "#") {
+			break
+		}
+
+		switch {
+		case strings.HasPrefix(owner, "@") && len(owner) > 1:
+			r.Owners = append(r.Owners, strings.ToLower(strings.TrimPrefix(owner, "@")))
+		case strings.Index(owner, "@") > 0:
+			emails++
+		default:
+			return Rule{}, fmt.Errorf("invalid owner %s", owner)
+		}
+	}
+
+	if emails != 0 && len(r.Owners) == 0 {
+		return Rule{}, fmt.Errorf("email owners only of %s", r.Pattern)
+	}
+
+	return r, nil
+}
+
+// Owner method returns the last rule matching the file path, and whether there is any.
+func (f *File) Owner(name string) (Rule, bool) {
+	for i := len(f.Rules) - 1; i >= 0; i-- {
+		if f.Rules[i].Match(name) {
+			return f.Rules[i], true
+		}
+	}
+
+	return Rule{}, false
+}
+
+// compile function translates the gitignore style pattern into a regular expression matching file
+// paths relative to the root of the repository. A pattern with a leading or middle slash is relative
+// to the root; otherwise it matches at any depth. A pattern matching a directory matches every file
+// in it, except that a trailing /* only matches the files directly in the directory. * matches
+// anything but a slash, ? matches any single character but a slash, and ** matches any number of
+// directories.
+func compile(pattern string) *regexp.Regexp {
+	p := pattern
+	anchored := strings.HasPrefix(p, "/") || strings.Contains(strings.TrimSuffix(p, "/"), "/")
+	p = strings.Trim(p, "/")
+
+	b := &bytes.Buffer{}
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+
+	segments := strings.Split(p, "/")
+	for i, seg := range segments {
+		last := i == len(segments)-1
+		if seg == "**" {
+			if last {
+				b.WriteString(".*")
+			} else {
+				// Zero or more directories, including the slash that follows.
+				b.WriteString("(?:.*/)?")
+			}
+
+			continue
+		}
+
+		for _, c := range seg {
+			switch c {
+			case '*':
+				b.WriteString("[^/]*")
+			case '?':
+				b.WriteString("[^/]")
+			default:
+				b.WriteString(regexp.QuoteMeta(string(c)))
+			}
+		}
+
+		if !last {
+			b.WriteString("/")
+		}
+	}
+
+	if !strings.HasSuffix(pattern, "/*") {
+		b.WriteString("(?:/.*)?")
+	}
+
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// DefaultCacheSize is how many CODEOWNERS files are cached by default.
+const DefaultCacheSize = 1000
+
+// Resolver reads CODEOWNERS files from GitHub. CODEOWNERS files are cached by the commit they are
+// read at, so the base SHA of a PR should be used instead of a branch name.
+type Resolver struct {
+	G *github.Client
+
+	// CacheSize is the most files cached, the oldest cached files being evicted first;
+	// DefaultCacheSize if not set.
+	CacheSize int
+
+	mu    sync.Mutex
+	files map[string]*File // key: owner/repo@ref; nil for no CODEOWNERS file.
+	order []string         // The keys of files, oldest first.
+}
+
+// Requirements method returns the approval requirements of the changed files at the given commit;
+// the requirements are keyed by the changed files with code owners. Team owners are listed as
+// org/team and need to be resolved to their members.
+func (r *Resolver) Requirements(owner, repo, ref string, files []string) (owners.Requirements, error) {
+	f, err := r.file(owner, repo, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	reqs := make(owners.Requirements)
+	if f == nil {
+		return reqs, nil
+	}
+
+	for _, name := range files {
+		if rule, ok := f.Owner(name); ok && len(rule.Owners) != 0 {
+			reqs[name] = rule.Owners
+		}
+	}
+
+	return reqs, nil
+}
+
+func (r *Resolver) file(owner, repo, ref string) (*File, error) {
+	key := owner + "/" + repo + "@" + ref
+
+	r.mu.Lock()
+	f, ok := r.files[key]
+	r.mu.Unlock()
+
+	if ok {
+		return f, nil
+	}
+
+	for _, p := range Paths {
+		opt := &github.RepositoryContentGetOptions{Ref: ref}
+		content, _, resp, err := r.G.Repositories.GetContents(owner, repo, p, opt)
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		b, err := content.Decode()
+		if err != nil {
+			return nil, err
+		}
+
+		if f, err = Parse(b); err != nil {
+			return nil, fmt.Errorf("%s: %v", p, err)
+		}
+
+		for _, skipped := range f.Skipped {
+			log.Printf("%s of %s/%s@%s: skipped %s", p, owner, repo, ref, skipped)
+		}
+
+		break
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.files == nil {
+		r.files = make(map[string]*File)
+	}
+
+	if _, ok := r.files[key]; !ok {
+		r.order = append(r.order, key)
+	}
+
+	r.files[key] = f
+
+	size := r.CacheSize
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+
+	for len(r.order) > size {
+		delete(r.files, r.order[0])
+		r.order = r.order[1:]
+	}
+
+	return f, nil
+}
@@ -0,0 +1,196 @@
+package codeowners_test
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/garukun/golgtm/pkg/lgtm/internal/codeowners"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/githubtest"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/owners"
+	"github.com/google/go-github/github"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		content string
+		rules   []codeowners.Rule
+		skipped int
+	}{
+		{
+			content: "# Comment\n\n*  @Neo  @garukun/Platform-Team\n/docs/ @smith trinity@example.com # Inline comment\nvendor/\n",
+			rules: []codeowners.Rule{
+				{Pattern: "*", Owners: []string{"neo", "garukun/platform-team"}},
+				{Pattern: "/docs/", Owners: []string{"smith"}},
+				{Pattern: "vendor/"},
+			},
+		},
+		{
+			content: "* @neo\n*.go neo\n/docs/ trinity@example.com\n*.md @ @trinity\n/pkg/ @smith\n",
+			rules: []codeowners.Rule{
+				{Pattern: "*", Owners: []string{"neo"}},
+				{Pattern: "/pkg/", Owners: []string{"smith"}},
+			},
+			skipped: 3,
+		},
+	}
+
+	for i, test := range tests {
+		t.Logf("Testing %d...", i)
+		f, err := codeowners.Parse([]byte(test.content))
+		if err != nil {
+			t.Errorf("Unexpected error %v.", err)
+			continue
+		}
+
+		if len(f.Skipped) != test.skipped {
+			t.Errorf("Expected %d lines skipped instead of %v.", test.skipped, f.Skipped)
+		}
+
+		if len(f.Rules) != len(test.rules) {
+			t.Errorf("Expected %d rules instead of %d.", len(test.rules), len(f.Rules))
+			continue
+		}
+
+		for j, r := range f.Rules {
+			if r.Pattern != test.rules[j].Pattern || !reflect.DeepEqual(r.Owners, test.rules[j].Owners) {
+				t.Errorf("Expected rule %s %v instead of %s %v.", test.rules[j].Pattern, test.rules[j].Owners, r.Pattern, r.Owners)
+			}
+		}
+	}
+}
+
+func TestRuleMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"*", "README.md", true},
+		{"*", "pkg/lgtm/lgtm.go", true},
+		{"*.go", "main.go", true},
+		{"*.go", "pkg/lgtm/lgtm.go", true},
+		{"*.go", "pkg/lgtm/lgtm.go.orig", false},
+		{"/*.go", "main.go", true},
+		{"/*.go", "pkg/main.go", false},
+		{"docs", "docs/index.md", true},
+		{"docs", "pkg/docs/index.md", true},
+		{"docs/", "pkg/docs/index.md", true},
+		{"/docs/", "docs/api/index.md", true},
+		{"/docs/", "pkg/docs/index.md", false},
+		{"docs/*", "docs/index.md", true},
+		{"docs/*", "docs/api/index.md", false},
+		{"apps/", "apps/web/main.go", true},
+		{"pkg/lgtm", "pkg/lgtm/lgtm.go", true},
+		{"pkg/lgtm", "cmd/pkg/lgtm/main.go", false},
+		{"**/logs", "logs/today.log", true},
+		{"**/logs", "build/deep/logs/today.log", true},
+		{"pkg/**", "pkg/lgtm/lgtm.go", true},
+		{"pkg/**", "cmd/pkg/main.go", false},
+		{"a/**/b", "a/b/c.go", true},
+		{"a/**/b", "a/x/y/b/c.go", true},
+		{"a/**/b", "a/x/c.go", false},
+		{"lgtm?.go", "pkg/lgtm1.go", true},
+		{"lgtm?.go", "pkg/lgtm.go", false},
+		{"v1.0", "v1x0", false},
+	}
+
+	for i, test := range tests {
+		t.Logf("Testing %d...", i)
+		f, err := codeowners.Parse([]byte(test.pattern + " @neo\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if match := f.Rules[0].Match(test.name); match != test.match {
+			t.Errorf("Expected %s to match %s: %t.", test.pattern, test.name, test.match)
+		}
+	}
+}
+
+func TestRequirements(t *testing.T) {
+	s := githubtest.NewServer()
+	defer s.Close()
+
+	s.SetFile("garukun", "golgtm", "base", ".github/CODEOWNERS", `
+*          @neo
+*.md       @trinity
+/pkg/      @garukun/platform
+/pkg/gen/
+`)
+
+	// Only the first CODEOWNERS file found is used.
+	s.SetFile("garukun", "golgtm", "base", "CODEOWNERS", "* @smith\n")
+
+	g := github.NewClient(nil)
+	g.BaseURL, _ = url.Parse(s.BaseURL())
+	r := &codeowners.Resolver{G: g}
+
+	tests := []struct {
+		ref      string
+		files    []string
+		expected owners.Requirements
+	}{
+		{
+			ref:   "base",
+			files: []string{"main.go", "README.md", "pkg/lgtm/lgtm.go", "pkg/gen/gen.go"},
+			expected: owners.Requirements{
+				"main.go":          {"neo"},
+				"README.md":        {"trinity"},
+				"pkg/lgtm/lgtm.go": {"garukun/platform"},
+			},
+		},
+		{ref: "base", files: []string{"pkg/gen/gen.go"}, expected: owners.Requirements{}},
+		{ref: "other", files: []string{"main.go"}, expected: owners.Requirements{}},
+	}
+
+	for i, test := range tests {
+		t.Logf("Testing %d...", i)
+		reqs, err := r.Requirements("garukun", "golgtm", test.ref, test.files)
+		if err != nil {
+			t.Errorf("Unexpected error %v.", err)
+		} else if !reflect.DeepEqual(reqs, test.expected) {
+			t.Errorf("Expected %v instead of %v.", test.expected, reqs)
+		}
+	}
+}
+
+func TestResolverCacheSize(t *testing.T) {
+	s := githubtest.NewServer()
+	defer s.Close()
+
+	s.SetFile("garukun", "golgtm", "a", "CODEOWNERS", "* @neo\n")
+	s.SetFile("garukun", "golgtm", "b", "CODEOWNERS", "* @trinity\n")
+
+	g := github.NewClient(nil)
+	g.BaseURL, _ = url.Parse(s.BaseURL())
+	r := &codeowners.Resolver{G: g, CacheSize: 1}
+
+	tests := []struct {
+		ref      string
+		content  string // The content of the file at the ref changed before the request, if any.
+		expected string // The owner of main.go.
+	}{
+		{ref: "a", expected: "neo"},
+		// Cached.
+		{ref: "a", content: "* @smith\n", expected: "neo"},
+		{ref: "b", expected: "trinity"},
+		// Evicted by b.
+		{ref: "a", expected: "smith"},
+	}
+
+	for i, test := range tests {
+		t.Logf("Testing %d...", i)
+
+		if len(test.content) != 0 {
+			s.SetFile("garukun", "golgtm", test.ref, "CODEOWNERS", test.content)
+		}
+
+		reqs, err := r.Requirements("garukun", "golgtm", test.ref, []string{"main.go"})
+		if err != nil {
+			t.Errorf("Unexpected error %v.", err)
+		} else if expected := (owners.Requirements{"main.go": {test.expected}}); !reflect.DeepEqual(reqs, expected) {
+			t.Errorf("Expected %v instead of %v.", expected, reqs)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+/*
+Package dashboard provides an HTML page of the review states of the open PRs of every configured
+repository, which may be filtered by the query parameters:
+
+	repo    the full name of a repository, e.g., garukun/golgtm;
+	state   in_review or approved;
+	author  the login of the author of the PRs.
+
+The page is also a natural target of the workflow context URL, e.g.,
+https://lgtm.example.com/dashboard/?repo=garukun/golgtm.
+
+The open PRs of every repository are listed via the API at most once per ListTTL, whereas their
+review states are always current.
+*/
+package dashboard
+
+import (
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/garukun/golgtm/pkg/lgtm/config"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/clock"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/pr"
+	"github.com/google/go-github/github"
+)
+
+// ListTTL is how long the open PRs of a repository are cached.
+const ListTTL = time.Minute
+
+// New function returns the http.Handler of the dashboard, which tells the time with the clock; the
+// system clock if nil.
+func New(u *pr.Updater, c clock.Clock) http.Handler {
+	if c == nil {
+		c = clock.System
+	}
+
+	return &dashboard{u: u, clock: c}
+}
+
+type dashboard struct {
+	u     *pr.Updater
+	clock clock.Clock
+
+	mu    sync.Mutex
+	pulls map[string]listing // key: lower case owner/repo.
+}
+
+// listing is the open PRs of a repository as listed at some point.
+type listing struct {
+	pulls   []*github.PullRequest
+	expires time.Time
+}
+
+// row is a PR listed on the dashboard.
+type row struct {
+	Repo      string
+	Number    int
+	Title     string
+	URL       string
+	Author    string
+	State     pr.State
+	Approvers []string
+	InState   string // How long the PR has been in the state, if known.
+	LastEvent string
+}
+
+type page struct {
+	Repos  []string
+	Repo   string
+	State  string
+	Author string
+	Rows   []row
+	Errors []string
+}
+
+var states = map[string]pr.State{
+	"in_review": pr.InReview,
+	"approved":  pr.Approved,
+}
+
+func (d *dashboard) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		resp.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := req.URL.Query()
+	p := &page{
+		Repo:   q.Get("repo"),
+		State:  q.Get("state"),
+		Author: q.Get("author"),
+	}
+
+	state, filterState := states[p.State]
+	if len(p.State) != 0 && !filterState {
+		http.Error(resp, "invalid state, expecting in_review or approved", http.StatusBadRequest)
+		return
+	}
+
+	for _, r := range d.u.Config.Github.Repos {
+		p.Repos = append(p.Repos, r.FullName())
+		if len(p.Repo) != 0 && !strings.EqualFold(p.Repo, r.FullName()) {
+			continue
+		}
+
+		rows, err := d.rows(r)
+		if err != nil {
+			log.Printf("cannot list PRs of %s: %v", r.FullName(), err)
+			p.Errors = append(p.Errors, fmt.Sprintf("Cannot list PRs of %s.", r.FullName()))
+			continue
+		}
+
+		for _, row := range rows {
+			if filterState && row.State != state || len(p.Author) != 0 && !strings.EqualFold(p.Author, row.Author) {
+				continue
+			}
+
+			p.Rows = append(p.Rows, row)
+		}
+	}
+
+	resp.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.Execute(resp, p); err != nil {
+		log.Printf("cannot render dashboard: %v", err)
+	}
+}
+
+// rows method returns the open PRs of the repository with their review states.
+func (d *dashboard) rows(repo config.Repo) ([]row, error) {
+	w := d.u.Workflow(pr.ID{Owner: repo.Owner, Repo: repo.Name})
+
+	pulls, err := d.list(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []row
+	for _, pull := range pulls {
+		id := pr.ID{Owner: repo.Owner, Repo: repo.Name, Number: *pull.Number}
+		r, err := d.u.Record(id)
+		if err != nil {
+			return nil, err
+		}
+
+		rows = append(rows, d.row(repo, pull, r, w))
+	}
+
+	return rows, nil
+}
+
+// list method returns the open PRs of the repository, which are only listed via the API if the
+// cached ones have expired.
+func (d *dashboard) list(repo config.Repo) ([]*github.PullRequest, error) {
+	key := strings.ToLower(repo.FullName())
+
+	d.mu.Lock()
+	l, ok := d.pulls[key]
+	d.mu.Unlock()
+
+	if ok && d.clock.Now().Before(l.expires) {
+		return l.pulls, nil
+	}
+
+	var pulls []*github.PullRequest
+	opt := &github.PullRequestListOptions{State: "open", ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		page, resp, err := d.u.G.PullRequests.List(repo.Owner, repo.Name, opt)
+		if err != nil {
+			return nil, err
+		}
+
+		pulls = append(pulls, page...)
+		if resp.NextPage == 0 {
+			break
+		}
+
+		opt.Page = resp.NextPage
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.pulls == nil {
+		d.pulls = make(map[string]listing)
+	}
+
+	d.pulls[key] = listing{pulls: pulls, expires: d.clock.Now().Add(ListTTL)}
+	return pulls, nil
+}
+
+func (d *dashboard) row(repo config.Repo, pull *github.PullRequest, r *pr.Record, w *config.Workflow) row {
+	rw := row{
+		Repo:   repo.FullName(),
+		Number: *pull.Number,
+		State:  r.State,
+	}
+
+	if pull.Title != nil {
+		rw.Title = *pull.Title
+	}
+
+	if pull.HTMLURL != nil {
+		rw.URL = *pull.HTMLURL
+	}
+
+	if pull.User != nil && pull.User.Login != nil {
+		rw.Author = *pull.User.Login
+	}
+
+	seen := make(map[string]bool)
+	phrases := []string{pr.ReviewApproval}
+	for _, t := range w.Approved.Triggers {
+		phrases = append(phrases, t.Phrase)
+	}
+
+	for _, phrase := range phrases {
+		for _, user := range r.Voters(phrase) {
+			if !seen[user] {
+				seen[user] = true
+				rw.Approvers = append(rw.Approvers, user)
+			}
+		}
+	}
+
+	sort.Strings(rw.Approvers)
+
+	now := d.clock.Now()
+	if !r.Since.IsZero() {
+		rw.InState = ago(now.Sub(r.Since))
+	}
+
+	if n := len(r.History); n != 0 {
+		e := r.History[n-1]
+		rw.LastEvent = e.Event
+		if len(e.Action) != 0 {
+			rw.LastEvent += " " + e.Action
+		}
+
+		if len(e.Actor) != 0 {
+			rw.LastEvent += " by @" + e.Actor
+		}
+
+		rw.LastEvent += ", " + ago(now.Sub(e.Time)) + " ago"
+	}
+
+	return rw
+}
+
+// ago function returns the rough duration in days, hours or minutes, e.g., 3d.
+func ago(d time.Duration) string {
+	switch {
+	case d >= 48*time.Hour:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	default:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+}
+
+var tmpl = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>LGTM Dashboard</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border-bottom: 1px solid #ddd; padding: 0.4em; text-align: left; }
+.approved { color: #28a745; }
+.in-review { color: #dbab09; }
+.error { color: #cb2431; }
+</style>
+</head>
+<body>
+<h1>LGTM Dashboard</h1>
+<form method="get">
+<select name="repo">
+<option value="">All repositories</option>
+{{range .Repos}}<option{{if eq . $.Repo}} selected{{end}}>{{.}}</option>
+{{end}}</select>
+<select name="state">
+<option value="">All states</option>
+<option value="in_review"{{if eq .State "in_review"}} selected{{end}}>In review</option>
+<option value="approved"{{if eq .State "approved"}} selected{{end}}>Approved</option>
+</select>
+<input name="author" placeholder="Author" value="{{.Author}}">
+<button type="submit">Filter</button>
+</form>
+{{range .Errors}}<p class="error">{{.}}</p>
+{{end}}<table>
+<tr><th>Repository</th><th>PR</th><th>Author</th><th>State</th><th>Approvers</th><th>In state for</th><th>Last event</th></tr>
+{{range .Rows}}<tr>
+<td>{{.Repo}}</td>
+<td><a href="{{.URL}}">#{{.Number}}</a> {{.Title}}</td>
+<td>{{.Author}}</td>
+<td class="{{if eq .State.String "approved"}}approved{{else}}in-review{{end}}">{{.State}}</td>
+<td>{{range $i, $a := .Approvers}}{{if $i}}, {{end}}@{{$a}}{{end}}</td>
+<td>{{.InState}}</td>
+<td>{{.LastEvent}}</td>
+</tr>
+{{else}}<tr><td colspan="7">No open PRs.</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
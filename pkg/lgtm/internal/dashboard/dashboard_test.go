@@ -0,0 +1,152 @@
+package dashboard_test
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/garukun/golgtm/pkg/lgtm/config"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/clock/clocktest"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/dashboard"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/githubtest"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/pr"
+	"github.com/google/go-github/github"
+)
+
+func TestDashboard(t *testing.T) {
+	s := githubtest.NewServer()
+	defer s.Close()
+
+	s.AddPull("garukun", "golgtm", githubtest.Pull{Number: 1, Title: "Fix the matrix", Author: "neo", HeadSHA: "a"})
+	s.AddPull("garukun", "golgtm", githubtest.Pull{Number: 2, Title: "Free zion", Author: "tank", HeadSHA: "b"})
+	s.AddPull("garukun", "golgtm", githubtest.Pull{Number: 3, Author: "neo", Closed: true})
+
+	g := github.NewClient(nil)
+	g.BaseURL, _ = url.Parse(s.BaseURL())
+
+	conf := &config.Config{}
+	conf.Github.Repos = []config.Repo{{Owner: "garukun", Name: "golgtm"}}
+	conf.Workflow.Approved.Triggers = config.Triggers{{Phrase: "lgtm", Count: 1}}
+
+	u := &pr.Updater{Logger: log.New(ioutil.Discard, "", 0), G: g, Config: conf}
+	u.Start()
+
+	id := pr.ID{Owner: "garukun", Repo: "golgtm", Number: 1}
+	u.Tally(id, "lgtm", "trinity")
+	u.Enqueue(pr.Update{ID: id, State: pr.Approved}, pr.Event{Event: "issue_comment", Action: "created", Actor: "trinity"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	u.Close(ctx)
+
+	h := dashboard.New(u, nil)
+
+	tests := []struct {
+		query    string
+		code     int
+		expected []string // Parts of the page.
+		absent   []string
+	}{
+		{
+			query: "",
+			code:  http.StatusOK,
+			expected: []string{
+				`>#1</a> Fix the matrix`,
+				`>#2</a> Free zion`,
+				`<td class="approved">approved</td>`,
+				`<td>@trinity</td>`,
+				`<td>issue_comment created by @trinity, 0m ago</td>`,
+			},
+			absent: []string{`>#3</a>`},
+		},
+		{query: "state=approved", code: http.StatusOK, expected: []string{`>#1</a>`}, absent: []string{`>#2</a>`}},
+		{query: "state=in_review&author=Tank", code: http.StatusOK, expected: []string{`>#2</a>`}, absent: []string{`>#1</a>`}},
+		{query: "repo=garukun/other", code: http.StatusOK, expected: []string{"No open PRs."}},
+		{query: "state=merged", code: http.StatusBadRequest},
+	}
+
+	for i, test := range tests {
+		t.Logf("Testing %d...", i)
+
+		resp := httptest.NewRecorder()
+		h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/?"+test.query, nil))
+		if resp.Code != test.code {
+			t.Errorf("Expected %d instead of %d.", test.code, resp.Code)
+			continue
+		}
+
+		body := resp.Body.String()
+		for _, e := range test.expected {
+			if !strings.Contains(body, e) {
+				t.Errorf("Expected %q in the page:\n%s", e, body)
+			}
+		}
+
+		for _, a := range test.absent {
+			if strings.Contains(body, a) {
+				t.Errorf("Expected no %q in the page:\n%s", a, body)
+			}
+		}
+	}
+}
+
+func TestDashboardListTTL(t *testing.T) {
+	s := githubtest.NewServer()
+	defer s.Close()
+
+	s.AddPull("garukun", "golgtm", githubtest.Pull{Number: 1, Author: "neo", HeadSHA: "a"})
+
+	g := github.NewClient(nil)
+	g.BaseURL, _ = url.Parse(s.BaseURL())
+
+	conf := &config.Config{}
+	conf.Github.Repos = []config.Repo{{Owner: "garukun", Name: "golgtm"}}
+
+	u := &pr.Updater{Logger: log.New(ioutil.Discard, "", 0), G: g, Config: conf}
+
+	clock := clocktest.New(time.Now())
+	h := dashboard.New(u, clock)
+
+	tests := []struct {
+		after    time.Duration // The time passed since the previous request.
+		pull     int           // The PR opened before the request, if any.
+		expected []string
+		absent   []string
+	}{
+		{expected: []string{`>#1</a>`}},
+		// Cached.
+		{after: dashboard.ListTTL / 2, pull: 2, expected: []string{`>#1</a>`}, absent: []string{`>#2</a>`}},
+		{after: dashboard.ListTTL / 2, expected: []string{`>#1</a>`, `>#2</a>`}},
+	}
+
+	for i, test := range tests {
+		t.Logf("Testing %d...", i)
+
+		clock.Add(test.after)
+		if test.pull != 0 {
+			s.AddPull("garukun", "golgtm", githubtest.Pull{Number: test.pull, Author: "tank", HeadSHA: "b"})
+		}
+
+		resp := httptest.NewRecorder()
+		h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		body := resp.Body.String()
+		for _, e := range test.expected {
+			if !strings.Contains(body, e) {
+				t.Errorf("Expected %q in the page:\n%s", e, body)
+			}
+		}
+
+		for _, a := range test.absent {
+			if strings.Contains(body, a) {
+				t.Errorf("Expected no %q in the page:\n%s", a, body)
+			}
+		}
+	}
+}
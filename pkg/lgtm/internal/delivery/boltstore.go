@@ -0,0 +1,102 @@
+package delivery
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// deliveriesBucket maps the delivery IDs to their keys in deliveryTimesBucket, which orders the
+	// delivery IDs by when they were seen.
+	deliveriesBucket    = []byte("deliveries")
+	deliveryTimesBucket = []byte("delivery_times")
+)
+
+// NewBoltStore function returns a Store that persists the delivery IDs in the given BoltDB, which
+// may be shared with other stores; the caller closes the BoltDB.
+func NewBoltStore(db *bolt.DB) (Store, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{deliveriesBucket, deliveryTimesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &boltStore{db: db}, nil
+}
+
+type boltStore struct {
+	db *bolt.DB
+}
+
+func (s *boltStore) AddDelivery(id string, now time.Time, ttl time.Duration) (bool, error) {
+	added := false
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		ids, times := tx.Bucket(deliveriesBucket), tx.Bucket(deliveryTimesBucket)
+
+		// Forget the expired IDs first; deleting while iterating would skip some.
+		var expired [][2][]byte
+		expiry := timeKey(now.Add(-ttl+1), "")
+		c := times.Cursor()
+		for k, v := c.First(); k != nil && bytes.Compare(k, expiry) < 0; k, v = c.Next() {
+			expired = append(expired, [2][]byte{append([]byte(nil), k...), append([]byte(nil), v...)})
+		}
+
+		for _, kv := range expired {
+			if err := times.Delete(kv[0]); err != nil {
+				return err
+			}
+
+			if err := ids.Delete(kv[1]); err != nil {
+				return err
+			}
+		}
+
+		if ids.Get([]byte(id)) != nil {
+			return nil
+		}
+
+		key := timeKey(now, id)
+		if err := ids.Put([]byte(id), key); err != nil {
+			return err
+		}
+
+		added = true
+		return times.Put(key, []byte(id))
+	})
+
+	return added, err
+}
+
+func (s *boltStore) RemoveDelivery(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		ids := tx.Bucket(deliveriesBucket)
+		key := ids.Get([]byte(id))
+		if key == nil {
+			return nil
+		}
+
+		if err := tx.Bucket(deliveryTimesBucket).Delete(key); err != nil {
+			return err
+		}
+
+		return ids.Delete([]byte(id))
+	})
+}
+
+// timeKey function returns the key of the delivery ID seen at the given time, which sorts by the
+// time.
+func timeKey(t time.Time, id string) []byte {
+	key := make([]byte, 8, 8+len(id))
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	return append(key, id...)
+}
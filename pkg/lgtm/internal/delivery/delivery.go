@@ -0,0 +1,131 @@
+// Package delivery remembers the IDs of the recent webhook deliveries, i.e., the X-GitHub-Delivery
+// headers, so that the redeliveries of GitHub are recognized.
+package delivery
+
+import (
+	"sync"
+	"time"
+
+	"github.com/garukun/golgtm/pkg/lgtm/internal/clock"
+)
+
+// Store persists the IDs of the deliveries, so that redeliveries are recognized across restarts.
+type Store interface {
+	// AddDelivery method records the delivery ID at the given time, unless the ID has been recorded
+	// within the TTL already, and returns whether the ID is recorded anew. The IDs recorded longer
+	// than the TTL ago are forgotten.
+	AddDelivery(id string, now time.Time, ttl time.Duration) (bool, error)
+
+	// RemoveDelivery method forgets the delivery ID.
+	RemoveDelivery(id string) error
+}
+
+// Cache is a bounded cache of the delivery IDs seen within the TTL.
+type Cache struct {
+	TTL  time.Duration
+	Size int // The most IDs cached in memory; unbounded if not positive.
+
+	// Store persists the IDs beyond the memory cache, if set.
+	Store Store
+
+	// Clock is the system clock if not set.
+	Clock clock.Clock
+
+	mu    sync.Mutex // Guards the memory cache, but is not held while calling the Store.
+	seq   uint64
+	seen  map[string]uint64 // value: the sequence number of when the ID was seen.
+	order []seen            // Oldest first, including the removed IDs.
+}
+
+type seen struct {
+	id   string
+	seq  uint64
+	time time.Time
+}
+
+// Add method records the delivery ID, and returns false if the ID has been recorded within the TTL
+// already, i.e., if the delivery is a redelivery.
+func (c *Cache) Add(id string) (bool, error) {
+	c.mu.Lock()
+	now := c.clock().Now()
+	c.prune(now)
+
+	if _, ok := c.seen[id]; ok {
+		c.mu.Unlock()
+		return false, nil
+	}
+
+	if c.Store == nil {
+		c.add(id, now)
+		c.mu.Unlock()
+		return true, nil
+	}
+
+	c.mu.Unlock()
+
+	// The Store tells the concurrent additions of the same ID apart on its own.
+	added, err := c.Store.AddDelivery(id, now, c.TTL)
+	if err != nil {
+		return false, err
+	}
+
+	// Seen before a restart, if not added; the time does not matter much.
+	c.mu.Lock()
+	c.add(id, now)
+	c.mu.Unlock()
+	return added, nil
+}
+
+// Remove method forgets the delivery ID, e.g., when the delivery could not be handled and should be
+// handled again if redelivered.
+func (c *Cache) Remove(id string) error {
+	c.mu.Lock()
+	delete(c.seen, id)
+	c.mu.Unlock()
+
+	if c.Store != nil {
+		return c.Store.RemoveDelivery(id)
+	}
+
+	return nil
+}
+
+func (c *Cache) add(id string, now time.Time) {
+	if c.seen == nil {
+		c.seen = make(map[string]uint64)
+	}
+
+	c.seq++
+	c.seen[id] = c.seq
+	c.order = append(c.order, seen{id: id, seq: c.seq, time: now})
+
+	for c.Size > 0 && len(c.seen) > c.Size {
+		c.pop()
+	}
+}
+
+// prune method forgets the IDs seen longer than the TTL ago.
+func (c *Cache) prune(now time.Time) {
+	for len(c.order) != 0 && now.Sub(c.order[0].time) >= c.TTL {
+		c.pop()
+	}
+}
+
+// pop method forgets the oldest ID.
+func (c *Cache) pop() {
+	s := c.order[0]
+	c.order = c.order[1:]
+
+	// The ID may have been removed, or removed and seen again since.
+	if seq, ok := c.seen[s.id]; ok && seq == s.seq {
+		delete(c.seen, s.id)
+	}
+}
+
+func (c *Cache) clock() clock.Clock {
+	if c.Clock == nil {
+		return clock.System
+	}
+
+	return c.Clock
+}
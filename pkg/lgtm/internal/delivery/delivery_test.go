@@ -0,0 +1,130 @@
+package delivery_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/garukun/golgtm/pkg/lgtm/internal/clock/clocktest"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/delivery"
+	bolt "go.etcd.io/bbolt"
+)
+
+// step is adding or removing a delivery ID after some time since the start.
+type step struct {
+	after  time.Duration
+	id     string
+	remove bool
+	added  bool // Whether the ID is expected to be added anew.
+}
+
+func TestCache(t *testing.T) {
+	tests := []struct {
+		size  int
+		steps []step
+	}{
+		// Redeliveries within the TTL are recognized.
+		{
+			steps: []step{
+				{id: "a", added: true},
+				{after: time.Minute, id: "b", added: true},
+				{after: 59 * time.Minute, id: "a"},
+				{after: time.Hour, id: "a", added: true},
+				{after: time.Hour, id: "b"},
+			},
+		},
+		// Only the most recent IDs are kept.
+		{
+			size: 2,
+			steps: []step{
+				{id: "a", added: true},
+				{id: "b", added: true},
+				{id: "c", added: true},
+				{id: "b"},
+				{id: "a", added: true},
+			},
+		},
+		// Removed IDs are added anew.
+		{
+			size: 2,
+			steps: []step{
+				{id: "a", added: true},
+				{id: "a", remove: true},
+				{id: "a", added: true},
+				{id: "b", added: true},
+				{id: "a"},
+			},
+		},
+	}
+
+	start := time.Now()
+	for i, test := range tests {
+		t.Logf("Testing %d...", i)
+
+		c := &delivery.Cache{TTL: time.Hour, Size: test.size}
+		run(t, c, start, test.steps)
+	}
+}
+
+func TestCacheStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lgtm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := bolt.Open(filepath.Join(dir, "lgtm.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ds, err := delivery.NewBoltStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+
+	// The store outlives the memory cache, e.g., across restarts or beyond its size.
+	c := &delivery.Cache{TTL: time.Hour, Size: 1, Store: ds}
+	run(t, c, start, []step{
+		{id: "a", added: true},
+		{id: "b", added: true},
+		{id: "c", added: true},
+		{id: "c", remove: true},
+	})
+
+	c = &delivery.Cache{TTL: time.Hour, Size: 1, Store: ds}
+	run(t, c, start, []step{
+		{after: time.Minute, id: "a"},
+		{after: time.Minute, id: "b"},
+		{after: time.Minute, id: "c", added: true},
+		{after: time.Hour, id: "a", added: true},
+	})
+}
+
+func run(t *testing.T, c *delivery.Cache, start time.Time, steps []step) {
+	clock := clocktest.New(start)
+	c.Clock = clock
+	for j, s := range steps {
+		clock.Set(start.Add(s.after))
+
+		if s.remove {
+			if err := c.Remove(s.id); err != nil {
+				t.Errorf("Unexpected error %v at step %d.", err, j)
+			}
+
+			continue
+		}
+
+		added, err := c.Add(s.id)
+		if err != nil {
+			t.Errorf("Unexpected error %v at step %d.", err, j)
+		} else if added != s.added {
+			t.Errorf("Expected %s added %t instead of %t at step %d.", s.id, s.added, added, j)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+/*
+Package githubapp authenticates GitHub API requests as the installations of a GitHub App, rather
+than as a user; see https://developer.github.com/apps/building-github-apps/authentication-options-for-github-apps/.
+
+The App signs short-lived JWTs with its private key and exchanges them for installation access
+tokens, which are cached until shortly before they expire. Requests about a repository are
+authenticated as the installation the repository belongs to, as told by the webhook payloads or
+looked up via the API.
+*/
+package githubapp
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/garukun/golgtm/pkg/lgtm/internal/clock"
+)
+
+// DefaultBaseURL is the base URL of the GitHub API.
+const DefaultBaseURL = "https://api.github.com/"
+
+// acceptHeader is the media type of the API endpoints of GitHub Apps, which are in preview.
+const acceptHeader = "application/vnd.github.machine-man-preview+json"
+
+const (
+	// jwtTTL is how long the JWT of the App is valid; GitHub allows at most 10 minutes.
+	jwtTTL = 9 * time.Minute
+
+	// refreshBefore is how long before an installation token expires that it is refreshed.
+	refreshBefore = 5 * time.Minute
+)
+
+// App is a GitHub App.
+type App struct {
+	ID  int
+	Key *rsa.PrivateKey
+
+	// BaseURL is the base URL of the GitHub API with a trailing slash; DefaultBaseURL if empty.
+	BaseURL string
+
+	// Client makes the requests for the installations and their tokens; http.DefaultClient if nil.
+	Client *http.Client
+
+	// Clock is the system clock if not set.
+	Clock clock.Clock
+
+	mu            sync.Mutex         // Not held while making requests.
+	tokens        map[int]*token     // key: installation ID.
+	requests      map[int]*tokenWait // The tokens being requested; key: installation ID.
+	installations map[string]int     // key: lower case owner/repo or owner; value: installation ID.
+}
+
+type token struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// tokenWait is the outcome of a token request that concurrent calls for the same installation wait
+// for.
+type tokenWait struct {
+	done  chan struct{} // Closed once the token is requested, or failed to be.
+	token string
+	err   error
+}
+
+// New function returns the App of the given ID with the PEM encoded RSA private key of the App.
+func New(id int, keyPEM []byte) (*App, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("no PEM private key")
+	}
+
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &App{ID: id, Key: key}, nil
+}
+
+// JWT method returns a JSON Web Token signed with RS256 that authenticates as the App itself.
+func (a *App) JWT() (string, error) {
+	now := a.clock().Now()
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := json.Marshal(map[string]int64{
+		// Allow for clock drift between here and GitHub.
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(jwtTTL).Unix(),
+		"iss": int64(a.ID),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, a.Key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// Token method returns an access token of the given installation, which is only requested from
+// GitHub if the cached one is about to expire. Concurrent calls for the same installation share a
+// single request, whereas the other installations are not held up by it.
+func (a *App) Token(installation int) (string, error) {
+	a.mu.Lock()
+	if t, ok := a.tokens[installation]; ok && a.clock().Now().Add(refreshBefore).Before(t.ExpiresAt) {
+		a.mu.Unlock()
+		return t.Token, nil
+	}
+
+	if w, ok := a.requests[installation]; ok {
+		a.mu.Unlock()
+		<-w.done
+		return w.token, w.err
+	}
+
+	w := &tokenWait{done: make(chan struct{})}
+	if a.requests == nil {
+		a.requests = make(map[int]*tokenWait)
+	}
+
+	a.requests[installation] = w
+	a.mu.Unlock()
+
+	t := &token{}
+	path := fmt.Sprintf("installations/%d/access_tokens", installation)
+	err := a.do(http.MethodPost, path, t)
+
+	a.mu.Lock()
+	delete(a.requests, installation)
+	if err != nil {
+		w.err = fmt.Errorf("cannot get token of installation %d: %v", installation, err)
+	} else {
+		if a.tokens == nil {
+			a.tokens = make(map[int]*token)
+		}
+
+		a.tokens[installation] = t
+		w.token = t.Token
+	}
+	a.mu.Unlock()
+
+	close(w.done)
+	return w.token, w.err
+}
+
+// SetInstallation method records the installation of the given repository, e.g., as told by a
+// webhook payload.
+func (a *App) SetInstallation(owner, repo string, id int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.installations == nil {
+		a.installations = make(map[string]int)
+	}
+
+	a.installations[strings.ToLower(owner+"/"+repo)] = id
+	a.installations[strings.ToLower(owner)] = id
+}
+
+// Installation method returns the ID of the installation of the given repository, looking it up
+// via the API if not recorded already.
+func (a *App) Installation(owner, repo string) (int, error) {
+	a.mu.Lock()
+	id, ok := a.installations[strings.ToLower(owner+"/"+repo)]
+	a.mu.Unlock()
+
+	if ok {
+		return id, nil
+	}
+
+	inst := &struct {
+		ID int `json:"id"`
+	}{}
+	if err := a.do(http.MethodGet, fmt.Sprintf("repos/%s/%s/installation", owner, repo), inst); err != nil {
+		return 0, fmt.Errorf("cannot find installation of %s/%s: %v", owner, repo, err)
+	}
+
+	a.SetInstallation(owner, repo, inst.ID)
+	return inst.ID, nil
+}
+
+// ownerInstallation method returns the ID of the recorded installation of the given owner, or the
+// only recorded installation if owner is empty.
+func (a *App) ownerInstallation(owner string) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(owner) != 0 {
+		if id, ok := a.installations[strings.ToLower(owner)]; ok {
+			return id, nil
+		}
+
+		return 0, fmt.Errorf("no installation of %s", owner)
+	}
+
+	id := 0
+	for _, i := range a.installations {
+		if id != 0 && i != id {
+			return 0, errors.New("cannot tell the installation among many")
+		}
+
+		id = i
+	}
+
+	if id == 0 {
+		return 0, errors.New("no installation")
+	}
+
+	return id, nil
+}
+
+// do method makes an API request authenticated as the App and decodes the JSON response into v.
+func (a *App) do(method, path string, v interface{}) error {
+	jwt, err := a.JWT()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(method, a.baseURL()+path, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", acceptHeader)
+
+	c := a.Client
+	if c == nil {
+		c = http.DefaultClient
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s %s: %s", method, req.URL, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (a *App) baseURL() string {
+	if len(a.BaseURL) == 0 {
+		return DefaultBaseURL
+	}
+
+	return a.BaseURL
+}
+
+func (a *App) clock() clock.Clock {
+	if a.Clock == nil {
+		return clock.System
+	}
+
+	return a.Clock
+}
@@ -0,0 +1,192 @@
+package githubapp_test
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/garukun/golgtm/pkg/lgtm/internal/clock/clocktest"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/githubapp"
+)
+
+const appID = 42
+
+// fakeGitHub function returns a fake GitHub API that issues a new token every time an installation
+// token is requested with a valid JWT, and echoes the authorization of the other requests.
+func fakeGitHub(t *testing.T, key *rsa.PublicKey, clock *clocktest.Clock) *httptest.Server {
+	issued := 0
+	return httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		var installation int
+		switch {
+		case req.URL.Path == "/repos/garukun/golgtm/installation":
+			verifyJWT(t, key, req)
+			fmt.Fprint(resp, `{"id": 7}`)
+		case req.Method == http.MethodPost && sscanf(req.URL.Path, "/installations/%d/access_tokens", &installation):
+			verifyJWT(t, key, req)
+			issued++
+			json.NewEncoder(resp).Encode(map[string]interface{}{
+				"token":      fmt.Sprintf("token-%d-%d", installation, issued),
+				"expires_at": clock.Now().Add(time.Hour),
+			})
+		case strings.HasPrefix(req.URL.Path, "/repos/garukun/unknown/"):
+			http.NotFound(resp, req)
+		default:
+			fmt.Fprint(resp, req.Header.Get("Authorization"))
+		}
+	}))
+}
+
+func sscanf(s, format string, v interface{}) bool {
+	n, err := fmt.Sscanf(s, format, v)
+	return n == 1 && err == nil
+}
+
+func verifyJWT(t *testing.T, key *rsa.PublicKey, req *http.Request) {
+	jwt := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		t.Errorf("Malformed JWT %q.", jwt)
+		return
+	}
+
+	sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], sig); err != nil {
+		t.Errorf("Invalid JWT signature: %v.", err)
+	}
+
+	claims := map[string]int64{}
+	b, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	if err := json.Unmarshal(b, &claims); err != nil || claims["iss"] != appID {
+		t.Errorf("Invalid JWT claims %s: %v.", b, err)
+	}
+}
+
+func newApp(t *testing.T, clock *clocktest.Clock) (*githubapp.App, *httptest.Server) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	app, err := githubapp.New(appID, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := fakeGitHub(t, &key.PublicKey, clock)
+	app.BaseURL = s.URL + "/"
+	app.Clock = clock
+
+	return app, s
+}
+
+func TestToken(t *testing.T) {
+	clock := clocktest.New(time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC))
+	app, s := newApp(t, clock)
+	defer s.Close()
+
+	tests := []struct {
+		installation int
+		advance      time.Duration
+		token        string
+	}{
+		{installation: 7, token: "token-7-1"},
+		// Cached until shortly before the token expires.
+		{installation: 7, advance: 50 * time.Minute, token: "token-7-1"},
+		{installation: 7, advance: 6 * time.Minute, token: "token-7-2"},
+		{installation: 8, token: "token-8-3"},
+		{installation: 7, token: "token-7-2"},
+	}
+
+	for i, test := range tests {
+		t.Logf("Testing %d...", i)
+		clock.Add(test.advance)
+
+		if token, err := app.Token(test.installation); err != nil {
+			t.Errorf("Unexpected error %v.", err)
+		} else if token != test.token {
+			t.Errorf("Expected token %s instead of %s.", test.token, token)
+		}
+	}
+}
+
+func TestTokenConcurrent(t *testing.T) {
+	clock := clocktest.New(time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC))
+	app, s := newApp(t, clock)
+	defer s.Close()
+
+	// Only one token is requested for the concurrent calls.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if token, err := app.Token(7); err != nil || token != "token-7-1" {
+				t.Errorf("Expected token token-7-1 instead of %s, %v.", token, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestTransport(t *testing.T) {
+	clock := clocktest.New(time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC))
+	app, s := newApp(t, clock)
+	defer s.Close()
+
+	c := &http.Client{Transport: app.Transport(nil)}
+	tests := []struct {
+		path string
+		auth string
+		err  bool
+	}{
+		// Not knowing any installation yet.
+		{path: "/teams/1/members", err: true},
+		// The installation of the repository is looked up.
+		{path: "/repos/garukun/golgtm/issues/1", auth: "token token-7-1"},
+		{path: "/repos/garukun/unknown/issues/1", err: true},
+		// The installation of the owner is known by now.
+		{path: "/orgs/garukun/teams", auth: "token token-7-1"},
+		{path: "/orgs/matrix/teams", err: true},
+		{path: "/teams/1/members", auth: "token token-7-1"},
+	}
+
+	for i, test := range tests {
+		t.Logf("Testing %d...", i)
+		resp, err := c.Get(s.URL + test.path)
+		if test.err && err == nil || !test.err && err != nil {
+			t.Errorf("The returned error %v did not meet the expectation.", err)
+			continue
+		} else if err != nil {
+			continue
+		}
+
+		b := make([]byte, 64)
+		n, _ := resp.Body.Read(b)
+		resp.Body.Close()
+
+		if auth := string(b[:n]); auth != test.auth {
+			t.Errorf("Expected authorization %q instead of %q.", test.auth, auth)
+		}
+	}
+
+	// Installations told by the webhooks are not looked up.
+	app.SetInstallation("Matrix", "zion", 8)
+	if id, err := app.Installation("matrix", "Zion"); err != nil || id != 8 {
+		t.Errorf("Expected installation 8 instead of %d, %v.", id, err)
+	}
+}
@@ -0,0 +1,67 @@
+package githubapp
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Transport method returns an http.RoundTripper that authenticates the API requests with the token
+// of the installation of the repository or the organization requested, before making the requests
+// with the base http.RoundTripper. Other requests are authenticated as the only installation known
+// so far.
+func (a *App) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &transport{app: a, base: base}
+}
+
+type transport struct {
+	app  *App
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	id, err := t.installation(req)
+	if err != nil {
+		return nil, err
+	}
+
+	tok, err := t.app.Token(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// A RoundTripper must not modify the request.
+	r := new(http.Request)
+	*r = *req
+	r.Header = make(http.Header, len(req.Header)+1)
+	for k, v := range req.Header {
+		r.Header[k] = append([]string(nil), v...)
+	}
+
+	r.Header.Set("Authorization", "token "+tok)
+	return t.base.RoundTrip(r)
+}
+
+// installation method returns the ID of the installation to authenticate the request as.
+func (t *transport) installation(req *http.Request) (int, error) {
+	base, err := url.Parse(t.app.baseURL())
+	if err != nil {
+		return 0, err
+	}
+
+	// e.g., repos/garukun/golgtm/issues/1/labels or orgs/garukun/teams.
+	path := strings.TrimPrefix(req.URL.Path, base.Path)
+	parts := strings.Split(path, "/")
+	switch {
+	case len(parts) >= 3 && parts[0] == "repos":
+		return t.app.Installation(parts[1], parts[2])
+	case len(parts) >= 2 && parts[0] == "orgs":
+		return t.app.ownerInstallation(parts[1])
+	default:
+		return t.app.ownerInstallation("")
+	}
+}
@@ -0,0 +1,76 @@
+package githubtest
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+)
+
+var deliveries int64
+
+// Delivery function returns a webhook request of the event with the JSON payload, signed with the
+// secret the same way as GitHub does.
+func Delivery(secret, event string, payload interface{}) *http.Request {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		panic(err)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	req.Header.Set("X-GitHub-Event", event)
+	req.Header.Set("X-GitHub-Delivery", fmt.Sprintf("delivery-%d", atomic.AddInt64(&deliveries, 1)))
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+// IssueCommentEvent function returns the payload of the user commenting on the pull request.
+func IssueCommentEvent(owner, name string, p Pull, user, body string) map[string]interface{} {
+	r := &repo{owner: owner, name: name, defaultBranch: "master"}
+	return map[string]interface{}{
+		"action":     "created",
+		"issue":      r.issueJSON(&p),
+		"comment":    map[string]interface{}{"body": body, "user": map[string]string{"login": user}},
+		"repository": r.json(),
+		"sender":     map[string]string{"login": user},
+	}
+}
+
+// PullRequestEvent function returns the payload of the action, e.g., synchronize, on the pull
+// request.
+func PullRequestEvent(owner, name string, p Pull, action, sender string) map[string]interface{} {
+	r := &repo{owner: owner, name: name, defaultBranch: "master"}
+	return map[string]interface{}{
+		"action":       action,
+		"number":       p.Number,
+		"pull_request": r.pullJSON(&p),
+		"repository":   r.json(),
+		"sender":       map[string]string{"login": sender},
+	}
+}
+
+// PullRequestReviewEvent function returns the payload of the action on the review of the user in
+// the state, e.g., submitted and approved.
+func PullRequestReviewEvent(owner, name string, p Pull, action, state, user string) map[string]interface{} {
+	r := &repo{owner: owner, name: name, defaultBranch: "master"}
+	return map[string]interface{}{
+		"action": action,
+		"review": map[string]interface{}{
+			"id":        len(p.Reviews) + 1,
+			"user":      map[string]string{"login": user},
+			"state":     state,
+			"commit_id": p.HeadSHA,
+		},
+		"pull_request": r.pullJSON(&p),
+		"repository":   r.json(),
+		"sender":       map[string]string{"login": user},
+	}
+}
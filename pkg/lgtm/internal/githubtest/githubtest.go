@@ -0,0 +1,761 @@
+/*
+Package githubtest provides an in-process fake of the GitHub API for tests, which keeps the pull
+requests of repositories with their labels, comments, statuses and reviews in memory, together with
+the files of the repositories.
+
+The fake only implements the API endpoints that LGTM uses. Error responses, e.g., server errors and
+rate limits, can be injected for matching requests.
+*/
+package githubtest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server is a fake GitHub API server.
+type Server struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	repos  map[string]*repo // key: lower case owner/repo.
+	teams  []*team
+	faults []*Fault
+	nextID int
+}
+
+// Pull is a pull request.
+type Pull struct {
+	Number  int
+	Title   string
+	Author  string
+	HeadSHA string
+	BaseSHA string
+	Closed  bool
+
+	Labels    []string
+	Files     []string // Names of the changed files.
+	Reviewers []string // Requested reviewers.
+
+	Comments []Comment
+	Statuses []Status
+	Reviews  []Review
+
+	// Updated is when the pull request was last updated; the creation time of the pull request if
+	// zero.
+	Updated time.Time
+}
+
+// Comment is an issue comment on a pull request.
+type Comment struct {
+	ID      int       `json:"id"`
+	User    string    `json:"-"`
+	Body    string    `json:"body"`
+	Created time.Time `json:"created_at"`
+}
+
+// Status is a commit status.
+type Status struct {
+	SHA         string `json:"-"`
+	State       string `json:"state"`
+	TargetURL   string `json:"target_url"`
+	Description string `json:"description"`
+	Context     string `json:"context"`
+}
+
+// Review is a pull request review.
+type Review struct {
+	ID        int       `json:"id"`
+	User      string    `json:"-"`
+	State     string    `json:"state"` // e.g., APPROVED.
+	CommitID  string    `json:"commit_id"`
+	Submitted time.Time `json:"submitted_at"`
+}
+
+// CheckRun is a check run of the Checks API.
+type CheckRun struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	HeadSHA    string `json:"head_sha"`
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion"`
+	Output     struct {
+		Title   string `json:"title"`
+		Summary string `json:"summary"`
+	} `json:"output"`
+}
+
+// Fault is an error response to the matching requests.
+type Fault struct {
+	Method  string // Any method if empty.
+	Path    string // Path prefix, e.g., /repos/garukun/golgtm/statuses; any path if empty.
+	Code    int
+	Header  http.Header
+	Message string
+	Times   int // Number of matching requests to fail; all of them if 0.
+}
+
+// ServerError function returns a Fault failing the matching requests with 500 Internal Server Error.
+func ServerError(method, path string, times int) Fault {
+	return Fault{Method: method, Path: path, Code: http.StatusInternalServerError, Message: "Server Error", Times: times}
+}
+
+// RateLimited function returns a Fault failing the matching requests as if the rate limit were
+// exhausted until reset.
+func RateLimited(method, path string, reset time.Time, times int) Fault {
+	return Fault{
+		Method: method,
+		Path:   path,
+		Code:   http.StatusForbidden,
+		Header: http.Header{
+			"X-Ratelimit-Limit":     {"5000"},
+			"X-Ratelimit-Remaining": {"0"},
+			"X-Ratelimit-Reset":     {strconv.FormatInt(reset.Unix(), 10)},
+		},
+		Message: "API rate limit exceeded for user.",
+		Times:   times,
+	}
+}
+
+type team struct {
+	org, slug string
+	members   []string
+}
+
+type repo struct {
+	owner, name   string
+	defaultBranch string
+	files         map[string]map[string]string // key: ref, then path; value: file content.
+	pulls         map[int]*Pull
+	commits       map[string]time.Time // key: SHA; value: commit time.
+	checkRuns     []*CheckRun          // Oldest first.
+}
+
+// NewServer function starts a fake GitHub API server, which should be closed after use.
+func NewServer() *Server {
+	s := &Server{repos: make(map[string]*repo)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// BaseURL method returns the base URL of the API for the GitHub client.
+func (s *Server) BaseURL() string {
+	return s.URL + "/"
+}
+
+// AddPull method adds the pull request to the repository, which is created if it does not exist.
+func (s *Server) AddPull(owner, name string, p Pull) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p.Updated.IsZero() {
+		p.Updated = time.Now()
+	}
+
+	r := s.repo(owner, name)
+	r.pulls[p.Number] = &p
+	if _, ok := r.commits[p.HeadSHA]; !ok && len(p.HeadSHA) != 0 {
+		r.commits[p.HeadSHA] = p.Updated
+	}
+}
+
+// Push method moves the head of the pull request to the commit SHA.
+func (s *Server) Push(owner, name string, number int, sha string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.repo(owner, name)
+	if p, ok := r.pulls[number]; ok {
+		p.HeadSHA = sha
+		p.Updated = time.Now()
+		r.commits[sha] = p.Updated
+	}
+}
+
+// SetTeam method sets the members of the team of the organization, which is created if it does not
+// exist.
+func (s *Server) SetTeam(org, slug string, members ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range s.teams {
+		if strings.EqualFold(t.org, org) && strings.EqualFold(t.slug, slug) {
+			t.members = members
+			return
+		}
+	}
+
+	s.teams = append(s.teams, &team{org: org, slug: slug, members: members})
+}
+
+// SetFile method sets the content of the file at the ref, e.g., the default branch or a commit SHA,
+// of the repository.
+func (s *Server) SetFile(owner, name, ref, path, content string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.repo(owner, name)
+	if r.files[ref] == nil {
+		r.files[ref] = make(map[string]string)
+	}
+
+	r.files[ref][path] = content
+}
+
+// Inject method makes the server fail the matching requests with the Fault. Faults are matched in
+// the order they are injected.
+func (s *Server) Inject(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults = append(s.faults, &f)
+}
+
+// Pull method returns a copy of the pull request, and whether it exists.
+func (s *Server) Pull(owner, name string, number int) (Pull, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.repo(owner, name).pulls[number]
+	if !ok {
+		return Pull{}, false
+	}
+
+	c := *p
+	c.Labels = append([]string(nil), p.Labels...)
+	c.Comments = append([]Comment(nil), p.Comments...)
+	c.Statuses = append([]Status(nil), p.Statuses...)
+	c.Reviews = append([]Review(nil), p.Reviews...)
+	return c, true
+}
+
+// Labels method returns the labels of the pull request.
+func (s *Server) Labels(owner, name string, number int) []string {
+	p, _ := s.Pull(owner, name, number)
+	return p.Labels
+}
+
+// Status method returns the latest status of the context on the commit, and whether there is any.
+func (s *Server) Status(owner, name, sha, context string) (Status, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, p := range s.repo(owner, name).pulls {
+		for i := len(p.Statuses) - 1; i >= 0; i-- {
+			if st := p.Statuses[i]; st.SHA == sha && st.Context == context {
+				return st, true
+			}
+		}
+	}
+
+	return Status{}, false
+}
+
+// CheckRun method returns the latest check run of the name on the commit, and whether there is any.
+func (s *Server) CheckRun(owner, name, sha, checkName string) (CheckRun, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	runs := s.repo(owner, name).checkRuns
+	for i := len(runs) - 1; i >= 0; i-- {
+		if runs[i].HeadSHA == sha && runs[i].Name == checkName {
+			return *runs[i], true
+		}
+	}
+
+	return CheckRun{}, false
+}
+
+// repo method returns the repository, which is created if it does not exist.
+func (s *Server) repo(owner, name string) *repo {
+	key := strings.ToLower(owner + "/" + name)
+	r, ok := s.repos[key]
+	if !ok {
+		r = &repo{
+			owner:         owner,
+			name:          name,
+			defaultBranch: "master",
+			files:         make(map[string]map[string]string),
+			pulls:         make(map[int]*Pull),
+			commits:       make(map[string]time.Time),
+		}
+		s.repos[key] = r
+	}
+
+	return r
+}
+
+func (s *Server) id() int {
+	s.nextID++
+	return s.nextID
+}
+
+// fault method returns the first injected Fault matching the request, if any.
+func (s *Server) fault(req *http.Request) *Fault {
+	for i, f := range s.faults {
+		if len(f.Method) != 0 && f.Method != req.Method || !strings.HasPrefix(req.URL.Path, f.Path) {
+			continue
+		}
+
+		if f.Times > 0 {
+			if f.Times--; f.Times == 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+		}
+
+		return f
+	}
+
+	return nil
+}
+
+// Installation is the ID of the installation of any GitHub App on every repository.
+const Installation = 1
+
+func (s *Server) serveHTTP(resp http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if f := s.fault(req); f != nil {
+		for k, v := range f.Header {
+			resp.Header()[k] = v
+		}
+
+		writeJSON(resp, f.Code, map[string]string{"message": f.Message})
+		return
+	}
+
+	// e.g., repos/garukun/golgtm/issues/1/labels.
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(parts) == 5 && parts[0] == "orgs" && parts[2] == "teams" && parts[4] == "members" {
+		s.serveTeams(resp, req, parts)
+		return
+	}
+
+	if len(parts) == 3 && parts[0] == "installations" && parts[2] == "access_tokens" && req.Method == http.MethodPost {
+		// Any GitHub App is installed everywhere, with tokens valid for an hour.
+		writeJSON(resp, http.StatusCreated, map[string]interface{}{
+			"token":      "installation-" + parts[1],
+			"expires_at": time.Now().Add(time.Hour),
+		})
+		return
+	}
+
+	if len(parts) < 3 || parts[0] != "repos" {
+		notFound(resp)
+		return
+	}
+
+	r := s.repo(parts[1], parts[2])
+	route := fmt.Sprintf("%s %s", req.Method, strings.Join(parts[3:], "/"))
+
+	switch n := number(parts); {
+	case route == "GET ":
+		writeJSON(resp, http.StatusOK, r.json())
+	case route == "GET installation":
+		writeJSON(resp, http.StatusOK, map[string]int{"id": Installation})
+	case route == "GET issues" || route == "GET pulls":
+		s.list(resp, req, r, parts[3] == "pulls")
+	case strings.HasPrefix(route, "GET contents/"):
+		s.contents(resp, req, r, strings.Join(parts[4:], "/"))
+	case strings.HasPrefix(route, "POST statuses/") && len(parts) == 5:
+		s.createStatus(resp, req, r, parts[4])
+	case route == "POST check-runs" || strings.HasPrefix(route, "PATCH check-runs/") && len(parts) == 5:
+		s.saveCheckRun(resp, req, r, parts[3:])
+	case strings.HasPrefix(route, "GET commits/") && len(parts) == 6 && parts[5] == "check-runs":
+		s.listCheckRuns(resp, req, r, parts[4])
+	case strings.HasPrefix(route, "GET commits/") && len(parts) == 6 && parts[5] == "statuses":
+		s.listStatuses(resp, req, r, parts[4])
+	case strings.HasPrefix(route, "GET commits/") && len(parts) == 5:
+		s.commit(resp, r, parts[4])
+	case n == 0:
+		notFound(resp)
+	case r.pulls[n] == nil:
+		notFound(resp)
+	case route == fmt.Sprintf("GET issues/%d", n):
+		writeJSON(resp, http.StatusOK, r.issueJSON(r.pulls[n]))
+	case route == fmt.Sprintf("PUT issues/%d/labels", n):
+		s.replaceLabels(resp, req, r.pulls[n])
+	case route == fmt.Sprintf("GET issues/%d/comments", n):
+		writeJSON(resp, http.StatusOK, commentsJSON(r.pulls[n].Comments))
+	case route == fmt.Sprintf("POST issues/%d/comments", n):
+		s.createComment(resp, req, r.pulls[n])
+	case route == fmt.Sprintf("GET pulls/%d", n):
+		writeJSON(resp, http.StatusOK, r.pullJSON(r.pulls[n]))
+	case route == fmt.Sprintf("GET pulls/%d/files", n):
+		files := make([]interface{}, len(r.pulls[n].Files))
+		for i, f := range r.pulls[n].Files {
+			files[i] = map[string]string{"filename": f, "status": "modified"}
+		}
+
+		writePage(resp, req, files)
+	case route == fmt.Sprintf("GET pulls/%d/reviews", n):
+		writeJSON(resp, http.StatusOK, reviewsJSON(r.pulls[n].Reviews))
+	case route == fmt.Sprintf("GET pulls/%d/requested_reviewers", n):
+		reviewers := []interface{}{}
+		for _, u := range r.pulls[n].Reviewers {
+			reviewers = append(reviewers, user(u))
+		}
+
+		writeJSON(resp, http.StatusOK, map[string]interface{}{"users": reviewers, "teams": []interface{}{}})
+	default:
+		notFound(resp)
+	}
+}
+
+// serveTeams method lists the members of a team of an organization.
+func (s *Server) serveTeams(resp http.ResponseWriter, req *http.Request, parts []string) {
+	if req.Method != http.MethodGet {
+		notFound(resp)
+		return
+	}
+
+	for _, t := range s.teams {
+		if strings.EqualFold(t.org, parts[1]) && strings.EqualFold(t.slug, parts[3]) {
+			members := make([]interface{}, len(t.members))
+			for i, m := range t.members {
+				members[i] = user(m)
+			}
+
+			writePage(resp, req, members)
+			return
+		}
+	}
+
+	notFound(resp)
+}
+
+// number function returns the issue or pull request number of the request path, if any.
+func number(parts []string) int {
+	if len(parts) < 5 || parts[3] != "issues" && parts[3] != "pulls" {
+		return 0
+	}
+
+	n, _ := strconv.Atoi(parts[4])
+	return n
+}
+
+func (s *Server) list(resp http.ResponseWriter, req *http.Request, r *repo, pulls bool) {
+	q := req.URL.Query()
+	state := q.Get("state")
+	if len(state) == 0 {
+		state = "open"
+	}
+
+	var labels []string
+	if l := q.Get("labels"); len(l) != 0 {
+		labels = strings.Split(l, ",")
+	}
+
+	numbers := make([]int, 0, len(r.pulls))
+	for n := range r.pulls {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+
+	var items []interface{}
+	for _, n := range numbers {
+		p := r.pulls[n]
+		if state != "all" && (state == "closed") != p.Closed || !hasLabels(p, labels) {
+			continue
+		}
+
+		if pulls {
+			items = append(items, r.pullJSON(p))
+		} else {
+			items = append(items, r.issueJSON(p))
+		}
+	}
+
+	writePage(resp, req, items)
+}
+
+func hasLabels(p *Pull, labels []string) bool {
+	for _, l := range labels {
+		found := false
+		for _, pl := range p.Labels {
+			found = found || strings.EqualFold(l, pl)
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *Server) contents(resp http.ResponseWriter, req *http.Request, r *repo, path string) {
+	ref := req.URL.Query().Get("ref")
+	if len(ref) == 0 {
+		ref = r.defaultBranch
+	}
+
+	content, ok := r.files[ref][path]
+	if !ok {
+		notFound(resp)
+		return
+	}
+
+	writeJSON(resp, http.StatusOK, map[string]interface{}{
+		"type":     "file",
+		"encoding": "base64",
+		"size":     len(content),
+		"path":     path,
+		"content":  base64.StdEncoding.EncodeToString([]byte(content)),
+	})
+}
+
+func (s *Server) createStatus(resp http.ResponseWriter, req *http.Request, r *repo, sha string) {
+	st := Status{SHA: sha}
+	if err := json.NewDecoder(req.Body).Decode(&st); err != nil {
+		writeJSON(resp, http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return
+	}
+
+	for _, p := range r.pulls {
+		if p.HeadSHA == sha {
+			p.Statuses = append(p.Statuses, st)
+		}
+	}
+
+	writeJSON(resp, http.StatusCreated, st)
+}
+
+func (s *Server) commit(resp http.ResponseWriter, r *repo, sha string) {
+	t, ok := r.commits[sha]
+	if !ok {
+		notFound(resp)
+		return
+	}
+
+	date := map[string]interface{}{"date": t}
+	writeJSON(resp, http.StatusOK, map[string]interface{}{
+		"sha":    sha,
+		"commit": map[string]interface{}{"author": date, "committer": date},
+	})
+}
+
+// listStatuses method lists the statuses of the commit, the most recent first.
+func (s *Server) listStatuses(resp http.ResponseWriter, req *http.Request, r *repo, sha string) {
+	var statuses []interface{}
+	for _, p := range r.pulls {
+		for i := len(p.Statuses) - 1; i >= 0; i-- {
+			if st := p.Statuses[i]; st.SHA == sha {
+				statuses = append(statuses, st)
+			}
+		}
+	}
+
+	writePage(resp, req, statuses)
+}
+
+func (s *Server) saveCheckRun(resp http.ResponseWriter, req *http.Request, r *repo, parts []string) {
+	run := &CheckRun{}
+	if len(parts) == 2 {
+		id, _ := strconv.Atoi(parts[1])
+		for _, c := range r.checkRuns {
+			if c.ID == id {
+				run = c
+			}
+		}
+
+		if run.ID == 0 {
+			notFound(resp)
+			return
+		}
+	}
+
+	if err := json.NewDecoder(req.Body).Decode(run); err != nil {
+		writeJSON(resp, http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return
+	}
+
+	if run.Status != "completed" {
+		run.Conclusion = ""
+	}
+
+	if run.ID == 0 {
+		run.ID = s.id()
+		r.checkRuns = append(r.checkRuns, run)
+	}
+
+	writeJSON(resp, http.StatusOK, run)
+}
+
+func (s *Server) listCheckRuns(resp http.ResponseWriter, req *http.Request, r *repo, sha string) {
+	name := req.URL.Query().Get("check_name")
+
+	runs := []*CheckRun{}
+	for i := len(r.checkRuns) - 1; i >= 0; i-- {
+		if c := r.checkRuns[i]; c.HeadSHA == sha && (len(name) == 0 || c.Name == name) {
+			runs = append(runs, c)
+		}
+	}
+
+	writeJSON(resp, http.StatusOK, map[string]interface{}{"total_count": len(runs), "check_runs": runs})
+}
+
+func (s *Server) replaceLabels(resp http.ResponseWriter, req *http.Request, p *Pull) {
+	var labels []string
+	if err := json.NewDecoder(req.Body).Decode(&labels); err != nil {
+		writeJSON(resp, http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return
+	}
+
+	p.Labels = labels
+	p.Updated = time.Now()
+	writeJSON(resp, http.StatusOK, labelsJSON(labels))
+}
+
+func (s *Server) createComment(resp http.ResponseWriter, req *http.Request, p *Pull) {
+	c := Comment{ID: s.id(), User: "golgtm", Created: time.Now()}
+	if err := json.NewDecoder(req.Body).Decode(&c); err != nil {
+		writeJSON(resp, http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return
+	}
+
+	p.Comments = append(p.Comments, c)
+	p.Updated = c.Created
+	writeJSON(resp, http.StatusCreated, commentsJSON([]Comment{c})[0])
+}
+
+func (r *repo) json() map[string]interface{} {
+	return map[string]interface{}{
+		"name":           r.name,
+		"full_name":      r.owner + "/" + r.name,
+		"owner":          user(r.owner),
+		"default_branch": r.defaultBranch,
+	}
+}
+
+func (r *repo) issueJSON(p *Pull) map[string]interface{} {
+	state := "open"
+	if p.Closed {
+		state = "closed"
+	}
+
+	return map[string]interface{}{
+		"number":       p.Number,
+		"state":        state,
+		"title":        p.Title,
+		"user":         user(p.Author),
+		"labels":       labelsJSON(p.Labels),
+		"updated_at":   p.Updated,
+		"html_url":     fmt.Sprintf("https://github.com/%s/%s/pull/%d", r.owner, r.name, p.Number),
+		"pull_request": map[string]string{"url": fmt.Sprintf("/repos/%s/%s/pulls/%d", r.owner, r.name, p.Number)},
+	}
+}
+
+func (r *repo) pullJSON(p *Pull) map[string]interface{} {
+	j := r.issueJSON(p)
+	delete(j, "labels")
+	delete(j, "pull_request")
+
+	var reviewers []interface{}
+	for _, u := range p.Reviewers {
+		reviewers = append(reviewers, user(u))
+	}
+
+	j["requested_reviewers"] = reviewers
+	j["head"] = map[string]interface{}{"sha": p.HeadSHA, "ref": "feature", "repo": r.json()}
+	j["base"] = map[string]interface{}{"sha": p.BaseSHA, "ref": r.defaultBranch, "repo": r.json()}
+	return j
+}
+
+func labelsJSON(labels []string) []interface{} {
+	j := make([]interface{}, len(labels))
+	for i, l := range labels {
+		j[i] = map[string]string{"name": l}
+	}
+
+	return j
+}
+
+func commentsJSON(comments []Comment) []interface{} {
+	j := make([]interface{}, len(comments))
+	for i, c := range comments {
+		j[i] = map[string]interface{}{
+			"id":         c.ID,
+			"body":       c.Body,
+			"user":       user(c.User),
+			"created_at": c.Created,
+		}
+	}
+
+	return j
+}
+
+func reviewsJSON(reviews []Review) []interface{} {
+	j := make([]interface{}, len(reviews))
+	for i, r := range reviews {
+		j[i] = map[string]interface{}{
+			"id":           r.ID,
+			"user":         user(r.User),
+			"state":        r.State,
+			"commit_id":    r.CommitID,
+			"submitted_at": r.Submitted,
+		}
+	}
+
+	return j
+}
+
+func user(login string) map[string]string {
+	return map[string]string{"login": login}
+}
+
+// writePage function writes the page of the items requested by the page and per_page parameters,
+// with the Link header to the next page if any.
+func writePage(resp http.ResponseWriter, req *http.Request, items []interface{}) {
+	q := req.URL.Query()
+	page, _ := strconv.Atoi(q.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	perPage, _ := strconv.Atoi(q.Get("per_page"))
+	if perPage < 1 {
+		perPage = 30
+	}
+
+	start := (page - 1) * perPage
+	if start > len(items) {
+		start = len(items)
+	}
+
+	end := start + perPage
+	if end < len(items) {
+		q.Set("page", strconv.Itoa(page+1))
+		next := *req.URL
+		next.RawQuery = q.Encode()
+		resp.Header().Set("Link", fmt.Sprintf(`<http://%s%s>; rel="next"`, req.Host, next.RequestURI()))
+	} else {
+		end = len(items)
+	}
+
+	items = items[start:end]
+	if items == nil {
+		items = []interface{}{}
+	}
+
+	writeJSON(resp, http.StatusOK, items)
+}
+
+func notFound(resp http.ResponseWriter) {
+	writeJSON(resp, http.StatusNotFound, map[string]string{"message": "Not Found"})
+}
+
+func writeJSON(resp http.ResponseWriter, code int, v interface{}) {
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(code)
+	json.NewEncoder(resp).Encode(v)
+}
@@ -0,0 +1,217 @@
+/*
+Package owners resolves Kubernetes style OWNERS files, which list the approvers of every directory
+of a repository.
+
+An OWNERS file applies to its directory and every subdirectory. The approvers of a directory are
+the approvers listed by the closest OWNERS file and the OWNERS files of all its parent directories.
+*/
+package owners
+
+import (
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/github"
+	"gopkg.in/yaml.v2"
+)
+
+// Filename is the name of the OWNERS files.
+const Filename = "OWNERS"
+
+// File is the content of an OWNERS file.
+type File struct {
+	Approvers []string `yaml:"approvers"`
+
+	// Reviewers are listed for the reviewers' information only; they cannot approve.
+	Reviewers []string `yaml:"reviewers"`
+}
+
+// Parse function parses the content of an OWNERS file.
+func Parse(b []byte) (*File, error) {
+	f := &File{}
+	if err := yaml.Unmarshal(b, f); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// DefaultCacheSize is how many OWNERS files, or their absence, are cached by default.
+const DefaultCacheSize = 10000
+
+// Resolver reads OWNERS files from GitHub. OWNERS files are cached by the commit they are read at,
+// so the base SHA of a PR should be used instead of a branch name.
+type Resolver struct {
+	G *github.Client
+
+	// CacheSize is the most files cached, the oldest cached files being evicted first;
+	// DefaultCacheSize if not set.
+	CacheSize int
+
+	mu    sync.Mutex
+	files map[string]*File // key: owner/repo@ref:dir; nil for no OWNERS file.
+	order []string         // The keys of files, oldest first.
+}
+
+// Requirements method returns the approval requirements of the changed files at the given commit.
+func (r *Resolver) Requirements(owner, repo, ref string, files []string) (Requirements, error) {
+	reqs := make(Requirements)
+	resolved := make(map[string]bool) // key: directory of a changed file.
+
+	for _, f := range files {
+		dir := path.Dir(f)
+		if resolved[dir] {
+			continue
+		}
+
+		resolved[dir] = true
+
+		// Walk up from the directory of the file to the root; the closest OWNERS file owns the file.
+		var owning string
+		var approvers []string
+		for d := dir; ; d = path.Dir(d) {
+			of, err := r.file(owner, repo, ref, d)
+			if err != nil {
+				return nil, err
+			}
+
+			if of != nil {
+				if len(owning) == 0 {
+					owning = d
+				}
+
+				approvers = append(approvers, of.Approvers...)
+			}
+
+			if d == "." {
+				break
+			}
+		}
+
+		if len(owning) == 0 {
+			// No OWNERS file applies to the file.
+			continue
+		}
+
+		reqs[owning] = normalize(append(reqs[owning], approvers...))
+	}
+
+	return reqs, nil
+}
+
+func (r *Resolver) file(owner, repo, ref, dir string) (*File, error) {
+	key := owner + "/" + repo + "@" + ref + ":" + dir
+
+	r.mu.Lock()
+	f, ok := r.files[key]
+	r.mu.Unlock()
+
+	if ok {
+		return f, nil
+	}
+
+	opt := &github.RepositoryContentGetOptions{Ref: ref}
+	content, _, resp, err := r.G.Repositories.GetContents(owner, repo, path.Join(dir, Filename), opt)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		f = nil
+	} else if err != nil {
+		return nil, err
+	} else {
+		b, err := content.Decode()
+		if err != nil {
+			return nil, err
+		}
+
+		if f, err = Parse(b); err != nil {
+			return nil, err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.files == nil {
+		r.files = make(map[string]*File)
+	}
+
+	if _, ok := r.files[key]; !ok {
+		r.order = append(r.order, key)
+	}
+
+	r.files[key] = f
+
+	size := r.CacheSize
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+
+	for len(r.order) > size {
+		delete(r.files, r.order[0])
+		r.order = r.order[1:]
+	}
+
+	return f, nil
+}
+
+// Requirements lists the approvers of every directory that owns some changed files; one of the
+// approvers of every directory must approve.
+type Requirements map[string][]string // key: directory with the OWNERS file; value: approvers.
+
+// IsApprover method returns whether the user can approve any of the directories.
+func (r Requirements) IsApprover(user string) bool {
+	user = strings.ToLower(user)
+	for _, approvers := range r {
+		for _, a := range approvers {
+			if a == user {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Pending method returns the sorted directories that none of the users can approve.
+func (r Requirements) Pending(users []string) []string {
+	approved := make(map[string]bool)
+	for _, u := range users {
+		approved[strings.ToLower(u)] = true
+	}
+
+	var pending []string
+	for dir, approvers := range r {
+		ok := false
+		for _, a := range approvers {
+			if approved[a] {
+				ok = true
+				break
+			}
+		}
+
+		if !ok {
+			pending = append(pending, dir)
+		}
+	}
+
+	sort.Strings(pending)
+	return pending
+}
+
+// normalize function returns the sorted and deduplicated lower case users.
+func normalize(users []string) []string {
+	set := make(map[string]struct{})
+	for _, u := range users {
+		set[strings.ToLower(u)] = struct{}{}
+	}
+
+	result := make([]string, 0, len(set))
+	for u := range set {
+		result = append(result, u)
+	}
+
+	sort.Strings(result)
+	return result
+}
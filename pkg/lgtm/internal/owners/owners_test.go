@@ -0,0 +1,97 @@
+package owners_test
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/garukun/golgtm/pkg/lgtm/internal/githubtest"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/owners"
+	"github.com/google/go-github/github"
+)
+
+func TestParse(t *testing.T) {
+	f, err := owners.Parse([]byte("approvers:\n- neo\n- trinity\nreviewers:\n- morpheus\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &owners.File{
+		Approvers: []string{"neo", "trinity"},
+		Reviewers: []string{"morpheus"},
+	}
+	if !reflect.DeepEqual(f, expected) {
+		t.Errorf("Expected %+v instead of %+v.", expected, f)
+	}
+}
+
+func TestRequirements(t *testing.T) {
+	reqs := owners.Requirements{
+		".":      {"neo"},
+		"matrix": {"neo", "trinity"},
+		"zion":   {"morpheus", "neo"},
+	}
+
+	tests := []struct {
+		users    []string
+		approver string
+		ok       bool
+		pending  []string
+	}{
+		{users: nil, approver: "smith", ok: false, pending: []string{".", "matrix", "zion"}},
+		{users: []string{"Trinity"}, approver: "Trinity", ok: true, pending: []string{".", "zion"}},
+		{users: []string{"trinity", "morpheus"}, approver: "morpheus", ok: true, pending: []string{"."}},
+		{users: []string{"neo"}, approver: "neo", ok: true, pending: nil},
+	}
+
+	for i, test := range tests {
+		t.Logf("Testing %d...", i)
+		if ok := reqs.IsApprover(test.approver); ok != test.ok {
+			t.Errorf("Expected %s to be an approver: %t.", test.approver, test.ok)
+		}
+
+		if pending := reqs.Pending(test.users); !reflect.DeepEqual(pending, test.pending) {
+			t.Errorf("Expected pending directories %v instead of %v.", test.pending, pending)
+		}
+	}
+}
+
+func TestResolverCacheSize(t *testing.T) {
+	s := githubtest.NewServer()
+	defer s.Close()
+
+	s.SetFile("garukun", "golgtm", "a", "OWNERS", "approvers:\n- neo\n")
+	s.SetFile("garukun", "golgtm", "b", "OWNERS", "approvers:\n- trinity\n")
+
+	g := github.NewClient(nil)
+	g.BaseURL, _ = url.Parse(s.BaseURL())
+	r := &owners.Resolver{G: g, CacheSize: 1}
+
+	tests := []struct {
+		ref      string
+		content  string // The content of the file at the ref changed before the request, if any.
+		expected string // The approver of main.go.
+	}{
+		{ref: "a", expected: "neo"},
+		// Cached.
+		{ref: "a", content: "approvers:\n- smith\n", expected: "neo"},
+		{ref: "b", expected: "trinity"},
+		// Evicted by b.
+		{ref: "a", expected: "smith"},
+	}
+
+	for i, test := range tests {
+		t.Logf("Testing %d...", i)
+
+		if len(test.content) != 0 {
+			s.SetFile("garukun", "golgtm", test.ref, "OWNERS", test.content)
+		}
+
+		reqs, err := r.Requirements("garukun", "golgtm", test.ref, []string{"main.go"})
+		if err != nil {
+			t.Errorf("Unexpected error %v.", err)
+		} else if expected := (owners.Requirements{".": {test.expected}}); !reflect.DeepEqual(reqs, expected) {
+			t.Errorf("Expected %v instead of %v.", expected, reqs)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package pr
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuditEntry is a review decision, i.e., a change of the review state of a PR or an override by an
+// administrator.
+type AuditEntry struct {
+	Time     time.Time
+	Owner    string
+	Repo     string
+	Number   int
+	HeadSHA  string
+	From     string // The review state before, e.g., in review.
+	To       string // The review state after, e.g., approved.
+	Event    string // GitHub event type, e.g. issue_comment.
+	Action   string // GitHub event action, e.g. created.
+	Delivery string // GitHub webhook delivery ID, if any.
+	Actor    string
+	Phrase   string // The trigger phrase said by the actor, if any.
+	Reason   string // Why an administrator overrode the review state, if so.
+	URL      string
+}
+
+// AuditLog records the review decisions.
+type AuditLog interface {
+	// Append method records the entry; the entries recorded are never changed.
+	Append(e AuditEntry) error
+
+	// Query method returns the entries of the PR, oldest first.
+	Query(id ID) ([]AuditEntry, error)
+
+	Close() error
+}
+
+// auditEntry function returns the audit entry of the event changing the review state of the PR
+// from the given state.
+func auditEntry(id ID, from State, e Event) AuditEntry {
+	return AuditEntry{
+		Time:     e.Time,
+		Owner:    id.Owner,
+		Repo:     id.Repo,
+		Number:   id.Number,
+		HeadSHA:  e.HeadSHA,
+		From:     from.String(),
+		To:       e.State.String(),
+		Event:    e.Event,
+		Action:   e.Action,
+		Delivery: e.Delivery,
+		Actor:    e.Actor,
+		Phrase:   e.Phrase,
+		Reason:   e.Reason,
+		URL:      e.URL,
+	}
+}
+
+// NewFileAuditLog function returns an AuditLog appending the entries as JSON lines to the file at
+// the given path; the file is created if it does not exist.
+func NewFileAuditLog(path string) (AuditLog, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileAuditLog{path: path, f: f}, nil
+}
+
+type fileAuditLog struct {
+	path string
+
+	mu sync.Mutex // Guards appending to f.
+	f  *os.File
+}
+
+func (l *fileAuditLog) Append(e AuditEntry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, err = l.f.Write(append(b, '\n'))
+	return err
+}
+
+func (l *fileAuditLog) Query(id ID) ([]AuditEntry, error) {
+	f, err := os.Open(l.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []AuditEntry
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		var e AuditEntry
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			// A partially written entry, e.g., when the disk was full.
+			continue
+		}
+
+		// GitHub owners and repositories are case insensitive.
+		if e.Number == id.Number && strings.EqualFold(e.Owner, id.Owner) && strings.EqualFold(e.Repo, id.Repo) {
+			entries = append(entries, e)
+		}
+	}
+
+	return entries, s.Err()
+}
+
+func (l *fileAuditLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.f.Close()
+}
@@ -0,0 +1,92 @@
+package pr_test
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/garukun/golgtm/pkg/lgtm/config"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/pr"
+)
+
+func TestAudit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lgtm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.jsonl")
+	audit, err := pr.NewFileAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u := &pr.Updater{Logger: log.New(ioutil.Discard, "", 0), Config: &config.Config{}, Audit: audit}
+	u.Start()
+
+	id := pr.ID{Owner: "garukun", Repo: "golgtm", Number: 1}
+	other := pr.ID{Owner: "garukun", Repo: "golgtm", Number: 2}
+	now := time.Now().UTC().Truncate(time.Second)
+
+	tests := []struct {
+		id    pr.ID
+		state pr.State
+		event pr.Event
+	}{
+		{id: id, state: pr.Approved, event: pr.Event{Time: now, Event: "issue_comment", Action: "created", Delivery: "d1", Actor: "trinity", Phrase: "lgtm"}},
+		// Events not changing the review state are not audited...
+		{id: id, state: pr.Approved, event: pr.Event{Time: now, Event: "issue_comment", Action: "created", Delivery: "d2", Actor: "neo", Phrase: "lgtm"}},
+		{id: other, state: pr.Approved, event: pr.Event{Time: now, Event: "issue_comment", Action: "created", Delivery: "d3", Actor: "neo", Phrase: "lgtm"}},
+		{id: id, state: pr.InReview, event: pr.Event{Time: now, Event: "pull_request", Action: "synchronize", Delivery: "d4"}},
+		// ...unless made by an administrator.
+		{id: id, state: pr.InReview, event: pr.Event{Time: now, Event: "admin", Action: "force", Actor: "admin", Reason: "oops"}},
+	}
+
+	for i, test := range tests {
+		t.Logf("Testing %d...", i)
+
+		if err := u.Enqueue(pr.Update{ID: test.id, State: test.state}, test.event); err != nil {
+			t.Errorf("Unexpected error %v.", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	u.Close(ctx)
+
+	expected := []pr.AuditEntry{
+		{Time: now, Owner: "garukun", Repo: "golgtm", Number: 1, From: "in review", To: "approved", Event: "issue_comment", Action: "created", Delivery: "d1", Actor: "trinity", Phrase: "lgtm"},
+		{Time: now, Owner: "garukun", Repo: "golgtm", Number: 1, From: "approved", To: "in review", Event: "pull_request", Action: "synchronize", Delivery: "d4"},
+		{Time: now, Owner: "garukun", Repo: "golgtm", Number: 1, From: "in review", To: "in review", Event: "admin", Action: "force", Actor: "admin", Reason: "oops"},
+	}
+
+	if err := audit.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The entries are appended to the existing file.
+	audit, err = pr.NewFileAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer audit.Close()
+
+	if err := audit.Append(pr.AuditEntry{Owner: "garukun", Repo: "other", Number: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := audit.Query(pr.ID{Owner: "Garukun", Repo: "GoLGTM", Number: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("Expected entries %+v instead of %+v.", expected, entries)
+	}
+}
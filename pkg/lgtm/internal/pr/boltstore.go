@@ -0,0 +1,72 @@
+package pr
+
+import (
+	"encoding/json"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var recordsBucket = []byte("records")
+
+// NewBoltStore function returns a Store that persists the records in the given BoltDB, which may be
+// shared with other stores; the caller closes the BoltDB after closing the Store.
+func NewBoltStore(db *bolt.DB) (Store, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(recordsBucket)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &boltStore{db: db}, nil
+}
+
+type boltStore struct {
+	db *bolt.DB
+}
+
+func (s *boltStore) Get(id ID) (*Record, error) {
+	var r *Record
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		r, err = decodeRecord(id, tx.Bucket(recordsBucket).Get([]byte(storeKey(id))))
+		return err
+	})
+
+	return r, err
+}
+
+func (s *boltStore) Update(id ID, fn func(r *Record) error) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(recordsBucket)
+		key := []byte(storeKey(id))
+
+		r, err := decodeRecord(id, b.Get(key))
+		if err != nil {
+			return err
+		}
+
+		if err := fn(r); err != nil {
+			return err
+		}
+
+		v, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+
+		return b.Put(key, v)
+	})
+}
+
+func (s *boltStore) Delete(id ID) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(recordsBucket).Delete([]byte(storeKey(id)))
+	})
+}
+
+func (s *boltStore) Close() error {
+	// The BoltDB is closed by the caller.
+	return nil
+}
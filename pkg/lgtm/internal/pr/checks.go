@@ -0,0 +1,196 @@
+package pr
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/garukun/golgtm/pkg/lgtm/config"
+)
+
+// checksAccept is the media type of the Checks API, which is in preview.
+const checksAccept = "application/vnd.github.antiope-preview+json"
+
+// maxSummaryEvents is the number of the most recent trigger events listed in a check run summary.
+const maxSummaryEvents = 20
+
+// checkRun is a check run of the Checks API, which the GitHub client does not support yet; see
+// https://developer.github.com/v3/checks/runs/.
+type checkRun struct {
+	ID          int64           `json:"id,omitempty"`
+	Name        string          `json:"name,omitempty"`
+	HeadSHA     string          `json:"head_sha,omitempty"`
+	DetailsURL  string          `json:"details_url,omitempty"`
+	Status      string          `json:"status,omitempty"`
+	Conclusion  string          `json:"conclusion,omitempty"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty"`
+	Output      *checkRunOutput `json:"output,omitempty"`
+}
+
+type checkRunOutput struct {
+	Title   string `json:"title"`
+	Summary string `json:"summary"`
+}
+
+// reportCheck method creates or updates the check run of the head commit of the PR with the state of
+// the update. The check run is in progress while the PR is in review, and completed once the PR is
+// approved, or once it needs more than approvals, e.g., approvals of specific OWNERS.
+func (u *Updater) reportCheck(up Update, w *config.Workflow) error {
+	r, err := u.Record(up.ID)
+	if err != nil {
+		return err
+	}
+
+	sha := *up.PullRequest.Head.SHA
+	run := checkRun{
+		Name:       w.Context.Name,
+		HeadSHA:    sha,
+		DetailsURL: w.Context.URL,
+		Status:     "in_progress",
+		Output: &checkRunOutput{
+			Title:   strings.Title(up.State.String()),
+			Summary: checkSummary(r, up, w),
+		},
+	}
+
+	run.Conclusion = checkConclusion(up)
+	if len(run.Conclusion) != 0 {
+		now := time.Now()
+		run.Status = "completed"
+		run.CompletedAt = &now
+	}
+
+	prev, err := u.checkRun(up.ID, w.Context.Name, sha)
+	if err != nil {
+		return err
+	}
+
+	// A completed check run cannot be in progress again, so a new one takes over.
+	method, path := "POST", fmt.Sprintf("repos/%s/%s/check-runs", up.Owner, up.Repo)
+	if prev.ID != 0 && (prev.Status != "completed" || run.Status == "completed") {
+		method, path = "PATCH", fmt.Sprintf("%s/%d", path, prev.ID)
+	}
+
+	req, err := u.G.NewRequest(method, path, &run)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", checksAccept)
+
+	_, err = u.G.Do(req, nil)
+	return err
+}
+
+// checkRun method returns the latest check run of the name on the head commit, as looked up via
+// the API rather than remembered, so that nothing is kept per commit and the check runs created
+// before a restart are found; the check run has no ID if there is none.
+func (u *Updater) checkRun(id ID, name, sha string) (checkRun, error) {
+	path := fmt.Sprintf("repos/%s/%s/commits/%s/check-runs?check_name=%s", id.Owner, id.Repo, sha, url.QueryEscape(name))
+	req, err := u.G.NewRequest("GET", path, nil)
+	if err != nil {
+		return checkRun{}, err
+	}
+
+	req.Header.Set("Accept", checksAccept)
+
+	list := &struct {
+		CheckRuns []checkRun `json:"check_runs"`
+	}{}
+	if _, err := u.G.Do(req, list); err != nil {
+		return checkRun{}, err
+	}
+
+	// The most recent check run comes first.
+	if len(list.CheckRuns) == 0 {
+		return checkRun{}, nil
+	}
+
+	return list.CheckRuns[0], nil
+}
+
+// checkConclusion function returns the conclusion of the check run of the update, or none while the
+// check run is in progress.
+func checkConclusion(up Update) string {
+	switch {
+	case up.State == Approved:
+		return "success"
+	case len(up.Description) != 0:
+		return "action_required"
+	default:
+		return ""
+	}
+}
+
+// checkSummary function returns the markdown summary of the review of the PR: the approvers, the
+// outstanding requirements and the triggers said.
+func checkSummary(r *Record, up Update, w *config.Workflow) string {
+	b := &bytes.Buffer{}
+
+	fmt.Fprintln(b, "#### Approvers")
+	phrases := []string{ReviewApproval}
+	for _, t := range w.Approved.Triggers {
+		phrases = append(phrases, t.Phrase)
+	}
+
+	approved := false
+	for _, phrase := range phrases {
+		for _, user := range r.Voters(phrase) {
+			approved = true
+			if phrase == ReviewApproval {
+				fmt.Fprintf(b, "- @%s approved by a review\n", user)
+			} else {
+				fmt.Fprintf(b, "- @%s said `%s`\n", user, phrase)
+			}
+		}
+	}
+
+	if !approved {
+		fmt.Fprintln(b, "None yet.")
+	}
+
+	if up.State != Approved {
+		fmt.Fprintln(b, "\n#### Outstanding requirements")
+		if len(up.Description) != 0 {
+			fmt.Fprintln(b, up.Description)
+		} else {
+			fmt.Fprintf(b, "Any of %s.\n", triggerList(w.Approved.Triggers))
+		}
+	}
+
+	var fired []Event
+	for _, e := range r.History {
+		if len(e.Phrase) != 0 {
+			fired = append(fired, e)
+		}
+	}
+
+	if n := len(fired); n > maxSummaryEvents {
+		fired = fired[n-maxSummaryEvents:]
+	}
+
+	if len(fired) != 0 {
+		fmt.Fprintln(b, "\n#### Triggers")
+	}
+
+	for _, e := range fired {
+		fmt.Fprintf(b, "- %s @%s: `%s`", e.Time.UTC().Format("2006-01-02 15:04 MST"), e.Actor, e.Phrase)
+		if len(e.URL) != 0 {
+			fmt.Fprintf(b, " ([link](%s))", e.URL)
+		}
+		fmt.Fprintln(b)
+	}
+
+	return b.String()
+}
+
+func triggerList(triggers config.Triggers) string {
+	s := make([]string, len(triggers))
+	for i, t := range triggers {
+		s[i] = fmt.Sprintf("`%s` from %d user(s)", t.Phrase, t.Count)
+	}
+
+	return strings.Join(s, ", ")
+}
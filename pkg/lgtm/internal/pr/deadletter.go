@@ -0,0 +1,87 @@
+package pr
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// maxDeadLetters is the number of the most recent dead letters kept by an Updater.
+const maxDeadLetters = 1000
+
+// ErrNoDeadLetter is returned when replaying a dead letter that does not exist.
+var ErrNoDeadLetter = errors.New("no such dead letter")
+
+// DeadLetter is an update that could not be applied to GitHub.
+type DeadLetter struct {
+	ID       uint64
+	Update   Update
+	Attempts int
+	Err      string
+	Time     time.Time
+}
+
+type deadLetters struct {
+	mu      sync.Mutex
+	seq     uint64
+	letters []DeadLetter // Oldest first.
+}
+
+func (u *Updater) deadLetter(up Update, attempts int, err error) {
+	d := &u.deadLetters
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.seq++
+	d.letters = append(d.letters, DeadLetter{
+		ID:       d.seq,
+		Update:   up,
+		Attempts: attempts,
+		Err:      err.Error(),
+		Time:     time.Now(),
+	})
+
+	if n := len(d.letters); n > maxDeadLetters {
+		d.letters = append([]DeadLetter(nil), d.letters[n-maxDeadLetters:]...)
+	}
+}
+
+// DeadLetters method returns the updates that could not be applied, oldest first.
+func (u *Updater) DeadLetters() []DeadLetter {
+	d := &u.deadLetters
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]DeadLetter(nil), d.letters...)
+}
+
+// Replay method removes the dead letter and evaluates the PR of its update again, queuing the
+// resulting update. The update of the dead letter itself is not applied, since its labels and review
+// state may have been superseded since. The dead letter is kept if the PR cannot be evaluated or the
+// update cannot be queued, e.g., once the Updater is closed, unless the queue is full, which keeps
+// the resulting update as a dead letter instead.
+func (u *Updater) Replay(id uint64, actor string) error {
+	d := &u.deadLetters
+	d.mu.Lock()
+
+	for i, l := range d.letters {
+		if l.ID != id {
+			continue
+		}
+
+		d.letters = append(d.letters[:i], d.letters[i+1:]...)
+		d.mu.Unlock()
+
+		err := u.reevaluate(l.Update.ID, Event{Event: "admin", Action: "replay", Actor: actor})
+		if err != nil && err != ErrQueueFull {
+			d.mu.Lock()
+			d.letters = append(d.letters, l)
+			d.mu.Unlock()
+		}
+
+		return err
+	}
+
+	d.mu.Unlock()
+	return ErrNoDeadLetter
+}
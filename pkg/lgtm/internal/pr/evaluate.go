@@ -0,0 +1,303 @@
+package pr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/garukun/golgtm/pkg/lgtm/config"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/owners"
+	"github.com/google/go-github/github"
+)
+
+// NotApprovedError is returned by Evaluate when there are not enough approvals.
+type NotApprovedError struct {
+	Counts []string // The eligible vote counts of every approved trigger, e.g., "lgtm 1/2".
+
+	// Uncovered are the changed files when OWNERS files are required but none covers any of them,
+	// so that nobody may approve the PR.
+	Uncovered []string
+}
+
+func (e *NotApprovedError) Error() string {
+	msg := "not enough approvals: " + strings.Join(e.Counts, ", ")
+	if len(e.Uncovered) != 0 {
+		msg += "; no OWNERS file covers " + strings.Join(e.Uncovered, ", ")
+	}
+
+	return msg
+}
+
+// Evaluate method decides whether the PR is approved by the votes so far under the approval
+// requirements of its workflow. A NotApprovedError is returned if there are not enough approvals
+// to update the PR at all. Approving reviews count towards every approved trigger.
+//
+// When OWNERS files are required, only the approvers listed by the OWNERS files covering the
+// changed files are counted, and every directory owning some changed files needs an approval. The
+// PR is kept in review until then, with the outstanding directories in the status description; the
+// NotApprovedError names the changed files if no OWNERS file covers any of them. A
+// required CODEOWNERS file works the same way, with the outstanding changed files in the
+// description.
+//
+// When teams are required, the PR is likewise kept in review until enough members of every team
+// have approved it. The author of the PR never counts towards any requirement.
+func (u *Updater) Evaluate(id ID) (*Update, error) {
+	w := u.Workflow(id)
+	update := &Update{ID: id, State: Approved}
+
+	eligible := func(user string) bool { return true }
+
+	var pull *github.PullRequest
+	var reqs requirements
+	var err error
+	switch {
+	case w.Approved.Owners || w.Approved.CodeOwners:
+		if pull, reqs, err = u.ownersRequirements(id, w); err != nil {
+			return nil, err
+		}
+
+		update.PullRequest = pull
+	case len(w.Approved.Teams) != 0:
+		if pull, _, err = u.G.PullRequests.Get(id.Owner, id.Repo, id.Number); err != nil {
+			return nil, err
+		}
+	}
+
+	// Authors cannot approve their own PRs under any requirements.
+	if pull != nil {
+		author := strings.ToLower(*pull.User.Login)
+		eligible = func(user string) bool { return user != author }
+
+		// Unless no code owners own the changed files, only the owners may approve.
+		if w.Approved.Owners || !reqs.empty() {
+			eligible = func(user string) bool { return user != author && reqs.isApprover(user) }
+		}
+	}
+
+	r, err := u.Record(id)
+	if err != nil {
+		return nil, err
+	}
+
+	approvers, counts, ok := approvers(r, w, eligible)
+	if !ok {
+		err := &NotApprovedError{Counts: counts}
+		if reqs.empty() {
+			err.Uncovered = reqs.uncovered
+		}
+
+		return nil, err
+	}
+
+	var needs []string
+	if pending := reqs.dirs.Pending(approvers); len(pending) != 0 {
+		for i, dir := range pending {
+			if dir == "." {
+				pending[i] = "/"
+			}
+		}
+
+		needs = append(needs, "approval for "+strings.Join(pending, ", "))
+	}
+
+	if pending := reqs.files.Pending(approvers); len(pending) != 0 {
+		needs = append(needs, "code owner approval for "+strings.Join(pending, ", "))
+	}
+
+	if len(w.Approved.Teams) != 0 {
+		pending, err := u.pendingTeams(w.Approved.Teams, approvers)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(pending) != 0 {
+			needs = append(needs, "approval from "+strings.Join(pending, ", "))
+		}
+	}
+
+	if len(needs) != 0 {
+		update.State = InReview
+		update.Description = "Needs " + strings.Join(needs, "; ")
+	}
+
+	return update, nil
+}
+
+// pendingTeams method returns the vote counts of the required teams without enough approvals from
+// their members, e.g., "org/team 0/1".
+func (u *Updater) pendingTeams(reqs config.TeamRequirements, approvers []string) ([]string, error) {
+	if u.Teams == nil {
+		return nil, errors.New("no teams resolver")
+	}
+
+	var pending []string
+	for _, req := range reqs {
+		org, slug := req.Org()
+		members, err := u.Teams.Members(org, slug)
+		if err != nil {
+			return nil, err
+		}
+
+		n := 0
+		for _, user := range approvers {
+			if members[strings.ToLower(user)] {
+				n++
+			}
+		}
+
+		if n < req.Approvals() {
+			pending = append(pending, fmt.Sprintf("%s %d/%d", req.Team, n, req.Approvals()))
+		}
+	}
+
+	return pending, nil
+}
+
+// approvers function returns the eligible users who have said any approved trigger phrase on the
+// PR or approved it by a review, the eligible vote counts of every trigger and whether any trigger
+// count is reached.
+func approvers(r *Record, w *config.Workflow, eligible func(user string) bool) ([]string, []string, bool) {
+	var approvers, counts []string
+	seen := make(map[string]bool)
+	ok := false
+
+	reviewers := r.Voters(ReviewApproval)
+	for _, t := range w.Approved.Triggers {
+		n := 0
+		counted := make(map[string]bool)
+		for _, user := range append(r.Voters(t.Phrase), reviewers...) {
+			if counted[user] || !eligible(user) {
+				continue
+			}
+
+			n++
+			counted[user] = true
+			if !seen[user] {
+				seen[user] = true
+				approvers = append(approvers, user)
+			}
+		}
+
+		ok = ok || n >= t.Count
+		counts = append(counts, fmt.Sprintf("%s %d/%d", t.Phrase, n, t.Count))
+	}
+
+	return approvers, counts, ok
+}
+
+// requirements are the approval requirements of the changed files of a PR by the OWNERS files and
+// the CODEOWNERS file, kept apart since their keys may look alike, e.g., docs.
+type requirements struct {
+	dirs  owners.Requirements // key: directory with the OWNERS file.
+	files owners.Requirements // key: changed file with code owners; the teams resolved to their members.
+
+	uncovered []string // The changed files if OWNERS files are required but none covers them.
+}
+
+func (r requirements) empty() bool {
+	return len(r.dirs) == 0 && len(r.files) == 0
+}
+
+// isApprover method returns whether the user can approve any of the requirements.
+func (r requirements) isApprover(user string) bool {
+	return r.dirs.IsApprover(user) || r.files.IsApprover(user)
+}
+
+// ownersRequirements method returns the PR and the approval requirements of its changed files by
+// the OWNERS files and the CODEOWNERS file, as required by the workflow.
+func (u *Updater) ownersRequirements(id ID, w *config.Workflow) (*github.PullRequest, requirements, error) {
+	var reqs requirements
+	if w.Approved.Owners && u.Owners == nil {
+		return nil, reqs, errors.New("no owners resolver")
+	}
+
+	if w.Approved.CodeOwners && u.CodeOwners == nil {
+		return nil, reqs, errors.New("no code owners resolver")
+	}
+
+	pull, _, err := u.G.PullRequests.Get(id.Owner, id.Repo, id.Number)
+	if err != nil {
+		return nil, reqs, err
+	}
+
+	files, err := u.changedFiles(id)
+	if err != nil {
+		return nil, reqs, err
+	}
+
+	if w.Approved.Owners {
+		if reqs.dirs, err = u.Owners.Requirements(id.Owner, id.Repo, *pull.Base.SHA, files); err != nil {
+			return nil, reqs, err
+		}
+
+		if len(reqs.dirs) == 0 {
+			reqs.uncovered = files
+		}
+	}
+
+	if w.Approved.CodeOwners {
+		code, err := u.CodeOwners.Requirements(id.Owner, id.Repo, *pull.Base.SHA, files)
+		if err != nil {
+			return nil, reqs, err
+		}
+
+		reqs.files = make(owners.Requirements)
+		for name, codeOwners := range code {
+			if reqs.files[name], err = u.teamMembers(codeOwners); err != nil {
+				return nil, reqs, err
+			}
+		}
+	}
+
+	return pull, reqs, nil
+}
+
+// teamMembers method returns the owners with the teams, i.e., org/team, replaced by their members.
+func (u *Updater) teamMembers(codeOwners []string) ([]string, error) {
+	var users []string
+	for _, owner := range codeOwners {
+		sep := strings.Index(owner, "/")
+		if sep < 0 {
+			users = append(users, owner)
+			continue
+		}
+
+		if u.Teams == nil {
+			return nil, errors.New("no teams resolver")
+		}
+
+		members, err := u.Teams.Members(owner[:sep], owner[sep+1:])
+		if err != nil {
+			return nil, err
+		}
+
+		for m := range members {
+			users = append(users, m)
+		}
+	}
+
+	return users, nil
+}
+
+// changedFiles method returns the names of all the files changed by the PR.
+func (u *Updater) changedFiles(id ID) ([]string, error) {
+	var names []string
+
+	opt := &github.ListOptions{PerPage: 100}
+	for {
+		files, resp, err := u.G.PullRequests.ListFiles(id.Owner, id.Repo, id.Number, opt)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, f := range files {
+			names = append(names, *f.Filename)
+		}
+
+		if resp.NextPage == 0 {
+			return names, nil
+		}
+
+		opt.Page = resp.NextPage
+	}
+}
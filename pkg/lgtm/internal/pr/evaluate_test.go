@@ -0,0 +1,287 @@
+package pr_test
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/garukun/golgtm/pkg/lgtm/config"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/codeowners"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/githubtest"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/owners"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/pr"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/teams"
+	"github.com/google/go-github/github"
+)
+
+func TestEvaluate(t *testing.T) {
+	conf := &config.Config{}
+	conf.Workflow.Approved.Triggers = config.Triggers{
+		{Phrase: "lgtm", Count: 2},
+		{Phrase: "ship it", Count: 1},
+	}
+
+	id := pr.ID{Owner: "garukun", Repo: "golgtm", Number: 1}
+	tests := []struct {
+		phrase string
+		user   string
+		err    bool
+	}{
+		{phrase: "lgtm", user: "neo", err: true},
+		{phrase: "lgtm", user: "neo", err: true},
+		{phrase: "lgtm", user: "trinity", err: false},
+		// Any trigger reaching its count approves.
+		{phrase: "ship it", user: "morpheus", err: false},
+	}
+
+	for i, test := range tests {
+		t.Logf("Testing %d...", i)
+		u := &pr.Updater{Config: conf}
+		for _, prev := range tests[:i+1] {
+			u.Tally(id, prev.phrase, prev.user)
+		}
+
+		if i == len(tests)-1 {
+			u.Reset(id)
+			u.Tally(id, test.phrase, test.user)
+		}
+
+		update, err := u.Evaluate(id)
+		if test.err && err == nil || !test.err && err != nil {
+			t.Errorf("The returned error %v did not meet the expectation.", err)
+		} else if err == nil && update.State != pr.Approved {
+			t.Errorf("Expected %s to be approved instead of %d.", id, update.State)
+		}
+	}
+}
+
+func TestEvaluateReviews(t *testing.T) {
+	conf := &config.Config{}
+	conf.Workflow.Approved.Triggers = config.Triggers{{Phrase: "lgtm", Count: 2}}
+
+	id := pr.ID{Owner: "garukun", Repo: "golgtm", Number: 1}
+	tests := []struct {
+		phrase string
+		user   string
+		revoke bool
+		err    bool
+	}{
+		{phrase: pr.ReviewApproval, user: "neo", err: true},
+		// The same user approving by a review and a comment only counts once.
+		{phrase: "lgtm", user: "neo", err: true},
+		{phrase: pr.ReviewApproval, user: "trinity", err: false},
+		// Dismissed reviews no longer count.
+		{phrase: pr.ReviewApproval, user: "trinity", revoke: true, err: true},
+		{phrase: "lgtm", user: "trinity", err: false},
+	}
+
+	u := &pr.Updater{Config: conf}
+	for i, test := range tests {
+		t.Logf("Testing %d...", i)
+		if test.revoke {
+			u.Revoke(id, test.phrase, test.user)
+		} else {
+			u.Tally(id, test.phrase, test.user)
+		}
+
+		update, err := u.Evaluate(id)
+		if _, ok := err.(*pr.NotApprovedError); err != nil && !ok {
+			t.Errorf("Unexpected error %v.", err)
+		} else if test.err && err == nil || !test.err && err != nil {
+			t.Errorf("The returned error %v did not meet the expectation.", err)
+		} else if err == nil && update.State != pr.Approved {
+			t.Errorf("Expected %s to be approved instead of %d.", id, update.State)
+		}
+	}
+}
+
+func TestEvaluateTeams(t *testing.T) {
+	s := githubtest.NewServer()
+	defer s.Close()
+
+	s.SetTeam("garukun", "platform", "neo", "trinity", "smith")
+	s.SetTeam("garukun", "web", "morpheus")
+	s.AddPull("garukun", "golgtm", githubtest.Pull{Number: 1, Author: "smith", HeadSHA: "head"})
+
+	g := github.NewClient(nil)
+	g.BaseURL, _ = url.Parse(s.BaseURL())
+
+	conf := &config.Config{}
+	conf.Workflow.Approved.Triggers = config.Triggers{{Phrase: "lgtm", Count: 1}}
+	conf.Workflow.Approved.Teams = config.TeamRequirements{
+		{Team: "garukun/platform", Count: 2},
+		{Team: "garukun/web"},
+	}
+
+	id := pr.ID{Owner: "garukun", Repo: "golgtm", Number: 1}
+	tests := []struct {
+		phrase      string
+		user        string
+		state       pr.State
+		description string
+	}{
+		{"lgtm", "tank", pr.InReview, "Needs approval from garukun/platform 0/2, garukun/web 0/1"},
+		// The author does not count, even as a member.
+		{"lgtm", "smith", pr.InReview, "Needs approval from garukun/platform 0/2, garukun/web 0/1"},
+		{pr.ReviewApproval, "neo", pr.InReview, "Needs approval from garukun/platform 1/2, garukun/web 0/1"},
+		{"lgtm", "Trinity", pr.InReview, "Needs approval from garukun/web 0/1"},
+		{"lgtm", "morpheus", pr.Approved, ""},
+	}
+
+	u := &pr.Updater{Config: conf, G: g, Teams: &teams.Resolver{G: g}}
+	for i, test := range tests {
+		t.Logf("Testing %d...", i)
+		u.Tally(id, test.phrase, test.user)
+
+		update, err := u.Evaluate(id)
+		if err != nil {
+			t.Errorf("Unexpected error %v.", err)
+			continue
+		}
+
+		if update.State != test.state || update.Description != test.description {
+			t.Errorf("Expected %s %q instead of %s %q.", test.state, test.description, update.State, update.Description)
+		}
+	}
+}
+
+func TestEvaluateCodeOwners(t *testing.T) {
+	s := githubtest.NewServer()
+	defer s.Close()
+
+	s.SetTeam("garukun", "platform", "trinity")
+	s.SetFile("garukun", "golgtm", "base", ".github/CODEOWNERS", "* @neo\n/pkg/ @garukun/platform\n")
+	s.AddPull("garukun", "golgtm", githubtest.Pull{
+		Number:  1,
+		Author:  "tank",
+		HeadSHA: "head",
+		BaseSHA: "base",
+		Files:   []string{"main.go", "pkg/lgtm/lgtm.go"},
+	})
+
+	g := github.NewClient(nil)
+	g.BaseURL, _ = url.Parse(s.BaseURL())
+
+	conf := &config.Config{}
+	conf.Workflow.Approved.Triggers = config.Triggers{{Phrase: "lgtm", Count: 1}}
+	conf.Workflow.Approved.CodeOwners = true
+
+	id := pr.ID{Owner: "garukun", Repo: "golgtm", Number: 1}
+	tests := []struct {
+		user        string
+		err         bool
+		state       pr.State
+		description string
+	}{
+		// Neither the author nor the users without code owners count.
+		{user: "tank", err: true},
+		{user: "morpheus", err: true},
+		{user: "trinity", state: pr.InReview, description: "Needs code owner approval for main.go"},
+		{user: "neo", state: pr.Approved},
+	}
+
+	u := &pr.Updater{
+		Config:     conf,
+		G:          g,
+		CodeOwners: &codeowners.Resolver{G: g},
+		Teams:      &teams.Resolver{G: g},
+	}
+	for i, test := range tests {
+		t.Logf("Testing %d...", i)
+		u.Tally(id, "lgtm", test.user)
+
+		update, err := u.Evaluate(id)
+		if _, ok := err.(*pr.NotApprovedError); err != nil && !ok {
+			t.Errorf("Unexpected error %v.", err)
+		} else if test.err != (err != nil) {
+			t.Errorf("The returned error %v did not meet the expectation.", err)
+		} else if err == nil && (update.State != test.state || update.Description != test.description) {
+			t.Errorf("Expected %s %q instead of %s %q.", test.state, test.description, update.State, update.Description)
+		}
+	}
+}
+
+func TestEvaluateOwnersAndCodeOwners(t *testing.T) {
+	s := githubtest.NewServer()
+	defer s.Close()
+
+	// The OWNERS directory and the CODEOWNERS pattern look alike.
+	s.SetFile("garukun", "golgtm", "base", "docs/OWNERS", "approvers:\n- trinity\n")
+	s.SetFile("garukun", "golgtm", "base", "CODEOWNERS", "docs @neo\n")
+	s.AddPull("garukun", "golgtm", githubtest.Pull{
+		Number:  1,
+		Author:  "tank",
+		HeadSHA: "head",
+		BaseSHA: "base",
+		Files:   []string{"docs/README.md"},
+	})
+
+	g := github.NewClient(nil)
+	g.BaseURL, _ = url.Parse(s.BaseURL())
+
+	conf := &config.Config{}
+	conf.Workflow.Approved.Triggers = config.Triggers{{Phrase: "lgtm", Count: 1}}
+	conf.Workflow.Approved.Owners = true
+	conf.Workflow.Approved.CodeOwners = true
+
+	id := pr.ID{Owner: "garukun", Repo: "golgtm", Number: 1}
+	tests := []struct {
+		user        string
+		state       pr.State
+		description string
+	}{
+		{user: "trinity", state: pr.InReview, description: "Needs code owner approval for docs/README.md"},
+		{user: "neo", state: pr.Approved},
+	}
+
+	u := &pr.Updater{
+		Config:     conf,
+		G:          g,
+		Owners:     &owners.Resolver{G: g},
+		CodeOwners: &codeowners.Resolver{G: g},
+	}
+	for i, test := range tests {
+		t.Logf("Testing %d...", i)
+		u.Tally(id, "lgtm", test.user)
+
+		update, err := u.Evaluate(id)
+		if err != nil {
+			t.Errorf("Unexpected error %v.", err)
+		} else if update.State != test.state || update.Description != test.description {
+			t.Errorf("Expected %s %q instead of %s %q.", test.state, test.description, update.State, update.Description)
+		}
+	}
+}
+
+func TestEvaluateOwnersUncovered(t *testing.T) {
+	s := githubtest.NewServer()
+	defer s.Close()
+
+	s.AddPull("garukun", "golgtm", githubtest.Pull{
+		Number:  1,
+		Author:  "tank",
+		HeadSHA: "head",
+		BaseSHA: "base",
+		Files:   []string{"main.go", "pkg/lgtm/lgtm.go"},
+	})
+
+	g := github.NewClient(nil)
+	g.BaseURL, _ = url.Parse(s.BaseURL())
+
+	conf := &config.Config{}
+	conf.Workflow.Approved.Triggers = config.Triggers{{Phrase: "lgtm", Count: 1}}
+	conf.Workflow.Approved.Owners = true
+
+	u := &pr.Updater{Config: conf, G: g, Owners: &owners.Resolver{G: g}}
+
+	id := pr.ID{Owner: "garukun", Repo: "golgtm", Number: 1}
+	u.Tally(id, "lgtm", "trinity")
+
+	// Nobody may approve without any OWNERS file, which the error tells.
+	_, err := u.Evaluate(id)
+	if e, ok := err.(*pr.NotApprovedError); !ok {
+		t.Errorf("Expected a NotApprovedError instead of %v.", err)
+	} else if !reflect.DeepEqual(e.Uncovered, []string{"main.go", "pkg/lgtm/lgtm.go"}) {
+		t.Errorf("Expected the changed files uncovered instead of %v.", e.Uncovered)
+	}
+}
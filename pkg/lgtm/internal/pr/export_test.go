@@ -0,0 +1,33 @@
+package pr
+
+import "time"
+
+func (r Retry) Do(stop <-chan struct{}, fn func() error) (int, error) {
+	return r.do(stop, fn)
+}
+
+func (r Retry) Backoff(retry int, err error) time.Duration {
+	return r.backoff(retry, err)
+}
+
+var Transient = transient
+
+// SameWorker method returns whether the updates of the PRs are applied by the same worker.
+func (u *Updater) SameWorker(a, b ID) bool {
+	u.init()
+	return u.queue(a) == u.queue(b)
+}
+
+// Queued method returns the updates queued for every worker.
+func (u *Updater) Queued() []Update {
+	u.init()
+
+	var updates []Update
+	for _, q := range u.queues {
+		q.mu.Lock()
+		updates = append(updates, q.updates...)
+		q.mu.Unlock()
+	}
+
+	return updates
+}
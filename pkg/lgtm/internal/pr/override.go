@@ -0,0 +1,98 @@
+package pr
+
+import "github.com/google/go-github/github"
+
+// Override method forces the review state of the PR regardless of its votes, e.g., when the
+// workflow got it wrong, and queues the update to be applied to GitHub. The reason is recorded in
+// the history of the PR and shown in the status description. The forced state holds until the next
+// event changing the review state of the PR.
+func (u *Updater) Override(id ID, s State, actor, reason string) error {
+	issue, pull, err := u.fetch(id)
+	if err != nil {
+		return err
+	}
+
+	u.Printf("%s forced %s as %s: %s", actor, id, s, reason)
+	return u.Enqueue(Update{
+		ID:          id,
+		State:       s,
+		Description: "Forced by an administrator: " + reason,
+		Issue:       issue,
+		PullRequest: pull,
+	}, Event{Event: "admin", Action: "force", Actor: actor, Reason: reason})
+}
+
+// forced function returns the event of an administrator forcing the review state of the PR, if it
+// is the most recent event of the PR.
+func forced(r *Record) (Event, bool) {
+	if len(r.History) == 0 {
+		return Event{}, false
+	}
+
+	e := r.History[len(r.History)-1]
+	return e, e.Event == "admin" && e.Action == "force"
+}
+
+// Clear method forgets all the votes on the PR and puts it back in review.
+func (u *Updater) Clear(id ID, actor, reason string) error {
+	issue, pull, err := u.fetch(id)
+	if err != nil {
+		return err
+	}
+
+	if err := u.Reset(id); err != nil {
+		return err
+	}
+
+	u.Printf("%s cleared the approvals of %s: %s", actor, id, reason)
+	return u.Enqueue(Update{
+		ID:          id,
+		State:       InReview,
+		Issue:       issue,
+		PullRequest: pull,
+	}, Event{Event: "admin", Action: "clear", Actor: actor, Reason: reason})
+}
+
+// Reevaluate method evaluates the votes on the PR again, e.g., after its workflow has changed, and
+// queues the resulting update.
+func (u *Updater) Reevaluate(id ID, actor string) error {
+	return u.reevaluate(id, Event{Event: "admin", Action: "evaluate", Actor: actor})
+}
+
+// reevaluate method evaluates the votes on the PR again and queues the resulting update, along with
+// the current labels and head commit of the PR, as the outcome of the event.
+func (u *Updater) reevaluate(id ID, e Event) error {
+	update, err := u.Evaluate(id)
+	if _, ok := err.(*NotApprovedError); ok {
+		update, err = &Update{ID: id, State: InReview}, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	issue, pull, err := u.fetch(id)
+	if err != nil {
+		return err
+	}
+
+	update.Issue = issue
+	update.PullRequest = pull
+
+	u.Printf("%s reevaluated %s as %s", e.Actor, id, update.State)
+	return u.Enqueue(*update, e)
+}
+
+// fetch method returns the issue and the PR to update.
+func (u *Updater) fetch(id ID) (*github.Issue, *github.PullRequest, error) {
+	issue, _, err := u.G.Issues.Get(id.Owner, id.Repo, id.Number)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pull, _, err := u.G.PullRequests.Get(id.Owner, id.Repo, id.Number)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return issue, pull, nil
+}
@@ -0,0 +1,166 @@
+package pr
+
+import (
+	"errors"
+	"expvar"
+	"hash/fnv"
+	"sync"
+)
+
+// ErrQueueFull is returned when enqueuing an update to a worker with too many updates queued.
+var ErrQueueFull = errors.New("update queue full")
+
+// coalescedUpdates counts the queued updates superseded by newer updates of the same PR.
+var coalescedUpdates = expvar.NewInt("coalesced_updates")
+
+const (
+	defaultWorkers   = 1
+	defaultQueueSize = 100
+)
+
+// queue is the updates waiting to be applied by a worker, oldest first.
+type queue struct {
+	// pushMu is held while recording and queuing an update, so that the updates are recorded in the
+	// order they are queued, without holding up the worker popping the updates meanwhile.
+	pushMu sync.Mutex
+
+	mu      sync.Mutex
+	cond    *sync.Cond // Signaled when an update is pushed or the queue is closed.
+	updates []Update
+	closed  bool
+}
+
+func newQueue() *queue {
+	q := &queue{}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// push method calls fn, e.g., to record the update, then queues the update unless fn fails.
+// ErrQueueFull is returned if the queue already has size updates.
+//
+// The update replaces the last queued update of the same PR if both are about the same head
+// commit, since only the newest review state matters; the labels and the status are written once.
+//
+// The pushes are serialized, including fn, e.g., writing to the store and the audit log; the worker
+// keeps popping the updates meanwhile.
+func (q *queue) push(up Update, size int, fn func() error) error {
+	q.pushMu.Lock()
+	defer q.pushMu.Unlock()
+
+	q.mu.Lock()
+	full := q.coalesce(up) < 0 && len(q.updates) >= size
+	q.mu.Unlock()
+
+	if full {
+		return ErrQueueFull
+	}
+
+	if fn != nil {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	// Only the worker may have popped updates since, e.g., the update to replace, freeing a slot.
+	if i := q.coalesce(up); i >= 0 {
+		// Some events do not pass the issue, whose labels are replaced all the same.
+		if up.Issue == nil {
+			up.Issue = q.updates[i].Issue
+		}
+
+		q.updates[i] = up
+		coalescedUpdates.Add(1)
+		return nil
+	}
+
+	q.updates = append(q.updates, up)
+	q.cond.Signal()
+	return nil
+}
+
+// coalesce method returns the index of the queued update that the update replaces, or -1 if none.
+func (q *queue) coalesce(up Update) int {
+	key := storeKey(up.ID)
+	for i := len(q.updates) - 1; i >= 0; i-- {
+		if storeKey(q.updates[i].ID) != key {
+			continue
+		}
+
+		// Only the last queued update of the PR may be replaced.
+		if headSHA(q.updates[i]) != headSHA(up) {
+			return -1
+		}
+
+		return i
+	}
+
+	return -1
+}
+
+// pop method waits for the oldest update and removes it from the queue; false is returned once the
+// queue is closed and empty.
+func (q *queue) pop() (Update, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.updates) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+
+	if len(q.updates) == 0 {
+		return Update{}, false
+	}
+
+	up := q.updates[0]
+	q.updates[0] = Update{}
+	q.updates = q.updates[1:]
+	return up, true
+}
+
+// close method stops the queue from waiting for more updates.
+func (q *queue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	q.cond.Broadcast()
+}
+
+// drain method removes and returns the updates in the queue.
+func (q *queue) drain() []Update {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	updates := q.updates
+	q.updates = nil
+	return updates
+}
+
+// headSHA function returns the head commit of the PR of the update, if known.
+func headSHA(up Update) string {
+	if up.PullRequest == nil || up.PullRequest.Head == nil || up.PullRequest.Head.SHA == nil {
+		return ""
+	}
+
+	return *up.PullRequest.Head.SHA
+}
+
+// queue method returns the queue of the worker applying the updates of the PR, so that the updates
+// of the same PR are applied in order.
+func (u *Updater) queue(id ID) *queue {
+	h := fnv.New32a()
+	h.Write([]byte(storeKey(id)))
+	return u.queues[h.Sum32()%uint32(len(u.queues))]
+}
+
+func (u *Updater) queueSize() int {
+	if u.QueueSize > 0 {
+		return u.QueueSize
+	}
+
+	return defaultQueueSize
+}
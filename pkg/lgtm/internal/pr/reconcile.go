@@ -0,0 +1,338 @@
+package pr
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/garukun/golgtm/pkg/lgtm/config"
+	"github.com/google/go-github/github"
+)
+
+// ReconcileAll method reconciles every open PR of every configured repository, until the stop
+// channel is closed, if any; see Reconcile.
+func (u *Updater) ReconcileAll(stop <-chan struct{}) {
+	for _, r := range u.Config.Github.Repos {
+		opt := &github.PullRequestListOptions{State: "open", ListOptions: github.ListOptions{PerPage: 100}}
+		for {
+			pulls, resp, err := u.G.PullRequests.List(r.Owner, r.Name, opt)
+			if err != nil {
+				u.Printf("cannot list PRs of %s to reconcile: %v", r.FullName(), err)
+				break
+			}
+
+			for _, pull := range pulls {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+
+				id := ID{Owner: r.Owner, Repo: r.Name, Number: *pull.Number}
+				if err := u.Reconcile(id); err != nil {
+					u.Printf("cannot reconcile %s: %v", id, err)
+				}
+			}
+
+			if resp.NextPage == 0 {
+				break
+			}
+
+			opt.Page = resp.NextPage
+		}
+	}
+}
+
+// Reconcile method recomputes the review state of the open PR from its comments and reviews, and
+// queues an update if the labels of the PR or the status of its head commit do not match, e.g.,
+// after a missed webhook delivery or a manual label edit. Every correction is logged. The votes
+// missed are added to the stored votes, whereas discarding the stored votes is left to the webhook
+// events. PRs whose review state an administrator forced last are left alone, since the forced state
+// holds until the next event; see Override.
+//
+// New commits invalidate the approvals, so only the comments and reviews since the head commit
+// count. Comments are dated by when they were made, so the comments made between committing the
+// head commit and pushing it count as well.
+func (u *Updater) Reconcile(id ID) error {
+	pull, _, err := u.G.PullRequests.Get(id.Owner, id.Repo, id.Number)
+	if err != nil {
+		return err
+	}
+
+	if pull.State == nil || *pull.State != "open" {
+		return nil
+	}
+
+	record, err := u.store().Get(id)
+	if err != nil {
+		return err
+	}
+
+	if e, ok := forced(record); ok {
+		u.Printf("not reconciling %s, forced as %s by %s: %s", id, e.State, e.Actor, e.Reason)
+		return nil
+	}
+
+	votes, err := u.recount(id, pull)
+	if err != nil {
+		return err
+	}
+
+	// The votes tallied by the webhook events while recounting are newer, so the recounted votes
+	// are merged into the stored ones rather than replacing them.
+	err = u.store().Update(id, func(r *Record) error {
+		for phrase, users := range votes {
+			for _, user := range users {
+				r.vote(phrase, user)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	update, err := u.Evaluate(id)
+	if _, ok := err.(*NotApprovedError); ok {
+		update, err = &Update{ID: id, State: InReview}, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	issue, _, err := u.G.Issues.Get(id.Owner, id.Repo, id.Number)
+	if err != nil {
+		return err
+	}
+
+	update.Issue = issue
+	update.PullRequest = pull
+
+	corrections, err := u.corrections(*update)
+	if err != nil {
+		return err
+	}
+
+	if len(corrections) == 0 {
+		return nil
+	}
+
+	u.Printf("reconciling %s as %s: %s", id, update.State, strings.Join(corrections, "; "))
+	return u.Enqueue(*update, Event{Event: "reconcile"})
+}
+
+// action is a comment or a review of a PR.
+type action struct {
+	time    time.Time
+	user    string
+	comment string
+	review  string // Lower case review state, e.g., approved; none for a comment.
+}
+
+type byTime []action
+
+func (a byTime) Len() int           { return len(a) }
+func (a byTime) Less(i, j int) bool { return a[i].time.Before(a[j].time) }
+func (a byTime) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
+// recount method returns the votes on the PR replayed from its comments and reviews since its head
+// commit, the same way as the webhook events count them.
+func (u *Updater) recount(id ID, pull *github.PullRequest) (map[string][]string, error) {
+	sha := *pull.Head.SHA
+	commit, _, err := u.G.Repositories.GetCommit(id.Owner, id.Repo, sha)
+	if err != nil {
+		return nil, err
+	}
+
+	var since time.Time
+	if commit.Commit != nil && commit.Commit.Committer != nil && commit.Commit.Committer.Date != nil {
+		since = *commit.Commit.Committer.Date
+	}
+
+	actions, err := u.comments(id, since)
+	if err != nil {
+		return nil, err
+	}
+
+	reviews, err := u.reviews(id, sha, since)
+	if err != nil {
+		return nil, err
+	}
+
+	actions = append(actions, reviews...)
+	sort.Stable(byTime(actions))
+
+	w := u.Workflow(id)
+	r := &Record{}
+	for _, a := range actions {
+		switch a.review {
+		case "approved":
+			r.vote(ReviewApproval, a.user)
+		case "changes_requested":
+			r.Votes = nil
+		case "":
+			if t, ok := w.Approved.Triggers.Match(a.comment); ok {
+				r.vote(t.Phrase, a.user)
+			} else if t, ok := w.InReview.Triggers.Match(a.comment); ok && r.vote(t.Phrase, a.user) >= t.Count {
+				r.Votes = nil
+			}
+		}
+	}
+
+	return r.Votes, nil
+}
+
+// comments method returns the comments on the PR made since the given time.
+func (u *Updater) comments(id ID, since time.Time) ([]action, error) {
+	var actions []action
+
+	opt := &github.IssueListCommentsOptions{Since: since, ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		comments, resp, err := u.G.Issues.ListComments(id.Owner, id.Repo, id.Number, opt)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, c := range comments {
+			// Since filters by when the comments were last updated.
+			if c.User == nil || c.Body == nil || c.CreatedAt == nil || c.CreatedAt.Before(since) {
+				continue
+			}
+
+			actions = append(actions, action{time: *c.CreatedAt, user: *c.User.Login, comment: *c.Body})
+		}
+
+		if resp.NextPage == 0 {
+			return actions, nil
+		}
+
+		opt.Page = resp.NextPage
+	}
+}
+
+// reviews method returns the reviews of the head commit of the PR submitted since the given time.
+// The GitHub client does not support the reviews API yet; see
+// https://developer.github.com/v3/pulls/reviews/.
+func (u *Updater) reviews(id ID, sha string, since time.Time) ([]action, error) {
+	var actions []action
+
+	path := fmt.Sprintf("repos/%s/%s/pulls/%d/reviews?per_page=100", id.Owner, id.Repo, id.Number)
+	for len(path) != 0 {
+		req, err := u.G.NewRequest("GET", path, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var reviews []struct {
+			User        *github.User `json:"user"`
+			State       string       `json:"state"`
+			CommitID    string       `json:"commit_id"`
+			SubmittedAt *time.Time   `json:"submitted_at"`
+		}
+		resp, err := u.G.Do(req, &reviews)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range reviews {
+			if r.User == nil || r.User.Login == nil || r.SubmittedAt == nil || r.SubmittedAt.Before(since) {
+				continue
+			}
+
+			if len(r.CommitID) != 0 && r.CommitID != sha {
+				continue
+			}
+
+			actions = append(actions, action{time: *r.SubmittedAt, user: *r.User.Login, review: strings.ToLower(r.State)})
+		}
+
+		path = ""
+		if resp.NextPage != 0 {
+			path = fmt.Sprintf("repos/%s/%s/pulls/%d/reviews?per_page=100&page=%d", id.Owner, id.Repo, id.Number, resp.NextPage)
+		}
+	}
+
+	return actions, nil
+}
+
+// corrections method returns what about the PR does not match the update, if anything.
+func (u *Updater) corrections(up Update) ([]string, error) {
+	w := u.Workflow(up.ID)
+
+	var corrections []string
+	if label := stateLabel(up.State, w); !hasLabel(up.Issue, label) || hasLabel(up.Issue, otherLabel(up.State, w)) {
+		corrections = append(corrections, fmt.Sprintf("label %q", label))
+	}
+
+	sha := *up.PullRequest.Head.SHA
+	if u.Checks {
+		run, err := u.checkRun(up.ID, w.Context.Name, sha)
+		if err != nil {
+			return nil, err
+		}
+
+		conclusion := checkConclusion(up)
+		if run.ID == 0 || run.Conclusion != conclusion || (run.Status == "completed") != (len(conclusion) != 0) {
+			corrections = append(corrections, fmt.Sprintf("check run %q on %s", up.State, sha))
+		}
+
+		return corrections, nil
+	}
+
+	status, err := u.status(up.ID, w, sha)
+	if err != nil {
+		return nil, err
+	}
+
+	if status == nil || status.State == nil || *status.State != commitStatus(up.State) ||
+		status.Description == nil || *status.Description != statusDescription(up, w) {
+		corrections = append(corrections, fmt.Sprintf("status %q on %s", commitStatus(up.State), sha))
+	}
+
+	return corrections, nil
+}
+
+// status method returns the latest status of the workflow context on the commit, if any.
+func (u *Updater) status(id ID, w *config.Workflow, sha string) (*github.RepoStatus, error) {
+	opt := &github.ListOptions{PerPage: 100}
+	for {
+		statuses, resp, err := u.G.Repositories.ListStatuses(id.Owner, id.Repo, sha, opt)
+		if err != nil {
+			return nil, err
+		}
+
+		// The most recent status comes first.
+		for _, s := range statuses {
+			if s.Context != nil && *s.Context == w.Context.Name {
+				return s, nil
+			}
+		}
+
+		if resp.NextPage == 0 {
+			return nil, nil
+		}
+
+		opt.Page = resp.NextPage
+	}
+}
+
+// otherLabel function returns the label of the review state other than the given one.
+func otherLabel(s State, w *config.Workflow) string {
+	if s == Approved {
+		return w.InReview.Label
+	}
+
+	return w.Approved.Label
+}
+
+func hasLabel(i *github.Issue, name string) bool {
+	for _, l := range i.Labels {
+		if l.Name != nil && *l.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
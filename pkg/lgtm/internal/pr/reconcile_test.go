@@ -0,0 +1,178 @@
+package pr_test
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/garukun/golgtm/pkg/lgtm/config"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/githubtest"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/pr"
+	"github.com/google/go-github/github"
+)
+
+func TestReconcile(t *testing.T) {
+	const statusContext = "LGTM Code Review"
+
+	pushed := time.Now().Add(-time.Hour)
+	before, after := pushed.Add(-time.Minute), pushed.Add(time.Minute)
+
+	tests := []struct {
+		pull     githubtest.Pull
+		tallied  []string // The users who said lgtm according to the webhook events.
+		forced   bool     // Whether an administrator forced the PR approved before reconciling.
+		labels   []string
+		status   string   // The expected status, if any.
+		statuses int      // The number of statuses after reconciling.
+		voters   []string // The users who said lgtm after reconciling.
+	}{
+		// Approvals since the head commit count.
+		{
+			pull: githubtest.Pull{
+				Labels:   []string{"bug", "Needs Review"},
+				Comments: []githubtest.Comment{{User: "trinity", Body: "lgtm", Created: after}},
+			},
+			labels:   []string{"bug", "Ready"},
+			status:   "success",
+			statuses: 1,
+			voters:   []string{"trinity"},
+		},
+		// The votes tallied meanwhile are kept.
+		{
+			pull: githubtest.Pull{
+				Labels:   []string{"Needs Review"},
+				Comments: []githubtest.Comment{{User: "trinity", Body: "lgtm", Created: after}},
+			},
+			tallied:  []string{"smith"},
+			labels:   []string{"Ready"},
+			status:   "success",
+			statuses: 1,
+			voters:   []string{"smith", "trinity"},
+		},
+		// Approvals before the head commit do not.
+		{
+			pull: githubtest.Pull{
+				Comments: []githubtest.Comment{{User: "trinity", Body: "lgtm", Created: before}},
+				Reviews:  []githubtest.Review{{User: "morpheus", State: "APPROVED", CommitID: "old", Submitted: after}},
+			},
+			labels:   []string{"Needs Review"},
+			status:   "pending",
+			statuses: 1,
+		},
+		// Requesting changes discards the approvals so far.
+		{
+			pull: githubtest.Pull{
+				Comments: []githubtest.Comment{{User: "trinity", Body: "lgtm", Created: after}},
+				Reviews:  []githubtest.Review{{User: "morpheus", State: "CHANGES_REQUESTED", CommitID: "head", Submitted: after.Add(time.Second)}},
+			},
+			labels:   []string{"Needs Review"},
+			status:   "pending",
+			statuses: 1,
+		},
+		// Approving reviews of the head commit count.
+		{
+			pull: githubtest.Pull{
+				Labels:  []string{"Needs Review"},
+				Reviews: []githubtest.Review{{User: "morpheus", State: "APPROVED", CommitID: "head", Submitted: after}},
+			},
+			labels:   []string{"Ready"},
+			status:   "success",
+			statuses: 1,
+		},
+		// Manual label edits are reverted.
+		{
+			pull: githubtest.Pull{
+				Labels:   []string{"Ready"},
+				Statuses: []githubtest.Status{{SHA: "head", State: "pending", Context: statusContext, Description: "LGTM Code Review workflow."}},
+			},
+			labels:   []string{"Needs Review"},
+			status:   "pending",
+			statuses: 2,
+		},
+		// Forced review states are left alone.
+		{
+			pull:     githubtest.Pull{Labels: []string{"Needs Review"}},
+			forced:   true,
+			labels:   []string{"Ready"},
+			status:   "success",
+			statuses: 1,
+		},
+		// PRs in sync are left alone.
+		{
+			pull: githubtest.Pull{
+				Labels:   []string{"Needs Review"},
+				Statuses: []githubtest.Status{{SHA: "head", State: "pending", Context: statusContext, Description: "LGTM Code Review workflow."}},
+			},
+			labels:   []string{"Needs Review"},
+			status:   "pending",
+			statuses: 1,
+		},
+	}
+
+	for i, test := range tests {
+		t.Logf("Testing %d...", i)
+
+		s := githubtest.NewServer()
+
+		test.pull.Number, test.pull.Author, test.pull.HeadSHA = 1, "neo", "head"
+		test.pull.Updated = pushed
+		s.AddPull("garukun", "golgtm", test.pull)
+
+		g := github.NewClient(nil)
+		g.BaseURL, _ = url.Parse(s.BaseURL())
+
+		conf := &config.Config{}
+		conf.Workflow.Context.Name = statusContext
+		conf.Workflow.Context.Description = "LGTM Code Review workflow."
+		conf.Workflow.InReview.Label = "Needs Review"
+		conf.Workflow.Approved.Label = "Ready"
+		conf.Workflow.Approved.Triggers = config.Triggers{{Phrase: "lgtm", Count: 1}}
+
+		u := &pr.Updater{Logger: log.New(ioutil.Discard, "", 0), G: g, Config: conf}
+		u.Start()
+
+		id := pr.ID{Owner: "garukun", Repo: "golgtm", Number: 1}
+		for _, user := range test.tallied {
+			u.Tally(id, "lgtm", user)
+		}
+
+		if test.forced {
+			if err := u.Override(id, pr.Approved, "admin", "hotfix"); err != nil {
+				t.Errorf("Unexpected error %v.", err)
+			}
+		}
+
+		if err := u.Reconcile(id); err != nil {
+			t.Errorf("Unexpected error %v.", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		u.Close(ctx)
+		cancel()
+
+		p, _ := s.Pull("garukun", "golgtm", 1)
+		if !reflect.DeepEqual(p.Labels, test.labels) {
+			t.Errorf("Expected labels %v instead of %v.", test.labels, p.Labels)
+		}
+
+		if st, _ := s.Status("garukun", "golgtm", "head", statusContext); st.State != test.status {
+			t.Errorf("Expected status %q instead of %q.", test.status, st.State)
+		}
+
+		if len(p.Statuses) != test.statuses {
+			t.Errorf("Expected %d statuses instead of %v.", test.statuses, p.Statuses)
+		}
+
+		if r, err := u.Record(id); err != nil {
+			t.Errorf("Unexpected error %v.", err)
+		} else if voters := r.Voters("lgtm"); !reflect.DeepEqual(voters, test.voters) {
+			t.Errorf("Expected voters %v instead of %v.", test.voters, voters)
+		}
+
+		s.Close()
+	}
+}
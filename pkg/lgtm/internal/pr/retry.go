@@ -0,0 +1,127 @@
+package pr
+
+import (
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// Retry configures how GitHub API calls failed with transient errors are retried.
+type Retry struct {
+	Attempts int           // Attempts in total, including the first one.
+	Delay    time.Duration // Delay before the first retry, doubled with every retry.
+	MaxDelay time.Duration // Delay cap; no cap if not set.
+}
+
+// do method calls fn until it succeeds, fails with a non-transient error, runs out of attempts or
+// stop is closed while waiting to retry, and returns the number of attempts made and the last error.
+func (r Retry) do(stop <-chan struct{}, fn func() error) (int, error) {
+	attempts := r.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	for i := 1; ; i++ {
+		err := fn()
+		if err == nil || i >= attempts || !transient(err) {
+			return i, err
+		}
+
+		select {
+		case <-time.After(r.backoff(i, err)):
+		case <-stop:
+			return i, err
+		}
+	}
+}
+
+// backoff method returns the jittered exponential delay before the given retry, unless GitHub asks
+// to retry after a specific delay. Either delay is capped by MaxDelay, so that a single response
+// cannot stall the worker indefinitely.
+//
+// Exhausted primary rate limits are waited out until they reset though, since the GitHub client
+// refuses the requests on its own until then; a retry any sooner would only waste an attempt.
+func (r Retry) backoff(retry int, err error) time.Duration {
+	if e, ok := err.(*github.RateLimitError); ok && !e.Rate.Reset.IsZero() {
+		if d := e.Rate.Reset.Sub(time.Now()); d > 0 {
+			return d
+		}
+
+		return 0
+	}
+
+	if d, ok := retryAfter(err); ok {
+		if r.MaxDelay > 0 && d > r.MaxDelay {
+			d = r.MaxDelay
+		}
+
+		if d < 0 {
+			d = 0
+		}
+
+		return d
+	}
+
+	d := r.Delay << uint(retry-1)
+	if r.MaxDelay > 0 && (d > r.MaxDelay || d <= 0) {
+		d = r.MaxDelay
+	}
+
+	if d <= 0 {
+		return 0
+	}
+
+	// Jitter the second half of the delay so that concurrent retries spread out.
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// retryAfter function returns the delay GitHub asks to retry after, if any.
+func retryAfter(err error) (time.Duration, bool) {
+	switch e := err.(type) {
+	case *github.AbuseRateLimitError:
+		if e.RetryAfter != nil {
+			return *e.RetryAfter, true
+		}
+	case *github.ErrorResponse:
+		if e.Response == nil {
+			break
+		}
+
+		if s, err := strconv.Atoi(e.Response.Header.Get("Retry-After")); err == nil {
+			return time.Duration(s) * time.Second, true
+		}
+	}
+
+	return 0, false
+}
+
+// transient function returns whether the error may go away by retrying, i.e., GitHub server errors,
+// primary and secondary rate limits, and network timeouts.
+func transient(err error) bool {
+	switch e := err.(type) {
+	case *github.RateLimitError, *github.AbuseRateLimitError:
+		return true
+	case *github.ErrorResponse:
+		if e.Response == nil {
+			return false
+		}
+
+		switch code := e.Response.StatusCode; {
+		case code >= http.StatusInternalServerError, code == http.StatusTooManyRequests:
+			return true
+		case code == http.StatusForbidden:
+			// Secondary rate limits come with a Retry-After header or a message about it.
+			return len(e.Response.Header.Get("Retry-After")) != 0 ||
+				strings.Contains(strings.ToLower(e.Message), "rate limit")
+		}
+	case net.Error:
+		return e.Timeout() || e.Temporary()
+	}
+
+	return false
+}
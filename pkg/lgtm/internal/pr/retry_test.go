@@ -0,0 +1,174 @@
+package pr_test
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/garukun/golgtm/pkg/lgtm/internal/pr"
+	"github.com/google/go-github/github"
+)
+
+func TestRetry(t *testing.T) {
+	githubErr := func(code int, msg string) error {
+		return &github.ErrorResponse{
+			Response: &http.Response{StatusCode: code, Header: make(http.Header)},
+			Message:  msg,
+		}
+	}
+
+	tests := []struct {
+		errs      []error // Errors returned by the calls in order; nil afterwards.
+		attempts  int
+		err       bool
+		transient bool // Whether the first error is transient.
+	}{
+		// Success
+		{errs: nil, attempts: 1, err: false},
+		// Transient errors are retried.
+		{errs: []error{githubErr(http.StatusBadGateway, "")}, attempts: 2, err: false, transient: true},
+		{errs: []error{githubErr(http.StatusForbidden, "You have exceeded a secondary rate limit.")}, attempts: 2, err: false, transient: true},
+		{errs: []error{rateLimited(time.Now())}, attempts: 2, err: false, transient: true},
+		{errs: []error{abuseRateLimited("")}, attempts: 2, err: false, transient: true},
+		{errs: []error{abuseRateLimited("0")}, attempts: 2, err: false, transient: true},
+		// Up to the attempts.
+		{
+			errs: []error{
+				githubErr(http.StatusServiceUnavailable, ""),
+				githubErr(http.StatusServiceUnavailable, ""),
+				githubErr(http.StatusServiceUnavailable, ""),
+			},
+			attempts:  3,
+			err:       true,
+			transient: true,
+		},
+		// Other errors are not retried.
+		{errs: []error{githubErr(http.StatusNotFound, "")}, attempts: 1, err: true},
+		{errs: []error{githubErr(http.StatusForbidden, "Resource not accessible")}, attempts: 1, err: true},
+		{errs: []error{errors.New("glitch")}, attempts: 1, err: true},
+	}
+
+	r := pr.Retry{Attempts: 3, Delay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+	for i, test := range tests {
+		t.Logf("Testing %d...", i)
+		if len(test.errs) != 0 && pr.Transient(test.errs[0]) != test.transient {
+			t.Errorf("Expected %v to be transient: %t.", test.errs[0], test.transient)
+		}
+
+		calls := 0
+		attempts, err := r.Do(nil, func() error {
+			calls++
+			if calls <= len(test.errs) {
+				return test.errs[calls-1]
+			}
+
+			return nil
+		})
+
+		if test.err && err == nil || !test.err && err != nil {
+			t.Errorf("The returned error %v did not meet the expectation.", err)
+		}
+
+		if attempts != test.attempts || calls != test.attempts {
+			t.Errorf("Expected %d attempts instead of %d with %d calls.", test.attempts, attempts, calls)
+		}
+	}
+}
+
+// checked function returns the error of the GitHub API response as returned by the GitHub client.
+func checked(code int, header http.Header, body string) error {
+	return github.CheckResponse(&http.Response{
+		StatusCode: code,
+		Header:     header,
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+		Request:    &http.Request{Method: http.MethodGet, URL: &url.URL{Path: "/repos/garukun/golgtm"}},
+	})
+}
+
+// rateLimited function returns the error of exhausting the primary rate limit until the given time.
+func rateLimited(reset time.Time) error {
+	header := http.Header{}
+	header.Set("X-RateLimit-Limit", "5000")
+	header.Set("X-RateLimit-Remaining", "0")
+	header.Set("X-RateLimit-Reset", fmt.Sprint(reset.Unix()))
+	return checked(http.StatusForbidden, header, `{"message": "API rate limit exceeded for garukun."}`)
+}
+
+// abuseRateLimited function returns the error of exceeding a secondary rate limit, with the given
+// Retry-After header if any.
+func abuseRateLimited(retryAfter string) error {
+	header := http.Header{}
+	if len(retryAfter) != 0 {
+		header.Set("Retry-After", retryAfter)
+	}
+
+	return checked(http.StatusForbidden, header, `{
+		"message": "You have triggered an abuse detection mechanism.",
+		"documentation_url": "https://developer.github.com/v3#abuse-rate-limits"
+	}`)
+}
+
+func TestBackoff(t *testing.T) {
+	retryAfter := func(s string) error {
+		header := http.Header{}
+		header.Set("Retry-After", s)
+		return checked(http.StatusServiceUnavailable, header, `{"message": "Unavailable"}`)
+	}
+
+	tests := []struct {
+		err      error
+		min, max time.Duration
+	}{
+		{err: errors.New("glitch"), min: 5 * time.Second, max: 10 * time.Second},
+		{err: retryAfter("3"), min: 3 * time.Second, max: 3 * time.Second},
+		// GitHub delays are capped.
+		{err: retryAfter("3600"), min: time.Minute, max: time.Minute},
+		{err: abuseRateLimited("30"), min: 30 * time.Second, max: 30 * time.Second},
+		{err: abuseRateLimited("3600"), min: time.Minute, max: time.Minute},
+		// Except the primary rate limit, waited out until it resets.
+		{err: rateLimited(time.Now().Add(time.Hour)), min: 59 * time.Minute, max: time.Hour},
+		{err: rateLimited(time.Now().Add(-time.Second)), min: 0, max: 0},
+	}
+
+	if _, ok := abuseRateLimited("").(*github.AbuseRateLimitError); !ok {
+		t.Fatalf("Expected an abuse rate limit error instead of %T.", abuseRateLimited(""))
+	}
+
+	if _, ok := rateLimited(time.Now()).(*github.RateLimitError); !ok {
+		t.Fatalf("Expected a rate limit error instead of %T.", rateLimited(time.Now()))
+	}
+
+	r := pr.Retry{Attempts: 3, Delay: 10 * time.Second, MaxDelay: time.Minute}
+	for i, test := range tests {
+		t.Logf("Testing %d...", i)
+
+		if d := r.Backoff(1, test.err); d < test.min || d > test.max {
+			t.Errorf("Expected a delay within [%v, %v] instead of %v.", test.min, test.max, d)
+		}
+	}
+}
+
+func TestRetryStop(t *testing.T) {
+	stop := make(chan struct{})
+	close(stop)
+
+	calls := 0
+	r := pr.Retry{Attempts: 3, Delay: time.Hour}
+	attempts, err := r.Do(stop, func() error {
+		calls++
+		return rateLimited(time.Now().Add(time.Hour))
+	})
+
+	if err == nil {
+		t.Error("Expected the rate limit error.")
+	}
+
+	if attempts != 1 || calls != 1 {
+		t.Errorf("Expected 1 attempt instead of %d with %d calls.", attempts, calls)
+	}
+}
@@ -0,0 +1,190 @@
+package pr
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxHistory is the number of the most recent events kept in a Record.
+const maxHistory = 100
+
+// Record is the review state of a PR.
+type Record struct {
+	ID
+	State   State
+	HeadSHA string
+	Since   time.Time // When the PR entered the state.
+
+	Votes   map[string][]string // key: trigger phrase; value: sorted lower case user logins.
+	History []Event             // The most recent events, oldest first.
+
+	// Reminded is the last reminder posted on the PR in its state, if any.
+	Reminded *Reminder `json:",omitempty"`
+}
+
+// Reminder is a reminder posted on a PR idle in review.
+type Reminder struct {
+	IdleSince time.Time // The last activity on the PR before the reminder.
+	Posted    time.Time // When the reminder was posted according to GitHub.
+	At        time.Time // When the reminder was posted according to the clock of the reminders.
+}
+
+// Event is something that happened to a PR while it is being reviewed.
+type Event struct {
+	Time     time.Time
+	Event    string // GitHub event type, e.g. issue_comment.
+	Action   string // GitHub event action, e.g. synchronize.
+	Delivery string // GitHub webhook delivery ID, if any.
+	Actor    string
+	Phrase   string // The trigger phrase said by the actor, if any.
+	State    State  // The state after the event.
+	HeadSHA  string
+	URL      string // Link to what the actor did, e.g., the comment.
+	Reason   string // Why an administrator overrode the review state, if so.
+}
+
+// Voters method returns the users who have said the given trigger phrase.
+func (r *Record) Voters(phrase string) []string {
+	return r.Votes[phrase]
+}
+
+// vote method records that the user has said the trigger phrase and returns the number of distinct
+// users who have said the phrase so far.
+func (r *Record) vote(phrase, user string) int {
+	if r.Votes == nil {
+		r.Votes = make(map[string][]string)
+	}
+
+	// GitHub logins are case insensitive.
+	user = strings.ToLower(user)
+	users := r.Votes[phrase]
+	i := sort.SearchStrings(users, user)
+	if i == len(users) || users[i] != user {
+		users = append(users, "")
+		copy(users[i+1:], users[i:])
+		users[i] = user
+		r.Votes[phrase] = users
+	}
+
+	return len(users)
+}
+
+// unvote method forgets that the user has said the trigger phrase.
+func (r *Record) unvote(phrase, user string) {
+	user = strings.ToLower(user)
+	users := r.Votes[phrase]
+	i := sort.SearchStrings(users, user)
+	if i == len(users) || users[i] != user {
+		return
+	}
+
+	r.Votes[phrase] = append(users[:i], users[i+1:]...)
+}
+
+// record method moves the PR to the state of the event and appends the event to the history. The
+// last reminder is forgotten once the PR changes state, so that the reminders start over.
+func (r *Record) record(e Event) {
+	if r.State != e.State || r.Since.IsZero() {
+		r.Since = e.Time
+		r.Reminded = nil
+	}
+
+	r.State = e.State
+	if len(e.HeadSHA) != 0 {
+		r.HeadSHA = e.HeadSHA
+	}
+
+	r.History = append(r.History, e)
+	if n := len(r.History); n > maxHistory {
+		r.History = append([]Event(nil), r.History[n-maxHistory:]...)
+	}
+}
+
+// Store persists the review state of PRs.
+type Store interface {
+	// Get method returns the record of the PR; an unknown PR has an empty record in review.
+	Get(id ID) (*Record, error)
+
+	// Update method atomically updates the record of the PR with fn. The record is not changed if
+	// fn returns an error.
+	Update(id ID, fn func(r *Record) error) error
+
+	// Delete method forgets the record of the PR, if any.
+	Delete(id ID) error
+
+	Close() error
+}
+
+// NewMemoryStore function returns a Store that keeps the records in memory, e.g., for testing.
+func NewMemoryStore() Store {
+	return &memoryStore{records: make(map[string][]byte)}
+}
+
+type memoryStore struct {
+	mu      sync.Mutex
+	records map[string][]byte // key: storeKey; value: JSON record.
+}
+
+func (s *memoryStore) Get(id ID) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return decodeRecord(id, s.records[storeKey(id)])
+}
+
+func (s *memoryStore) Update(id ID, fn func(r *Record) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := storeKey(id)
+	r, err := decodeRecord(id, s.records[key])
+	if err != nil {
+		return err
+	}
+
+	if err := fn(r); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	s.records[key] = b
+	return nil
+}
+
+func (s *memoryStore) Delete(id ID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, storeKey(id))
+	return nil
+}
+
+func (s *memoryStore) Close() error {
+	return nil
+}
+
+// storeKey function returns the key of the PR in a Store; GitHub owners and repositories are case
+// insensitive.
+func storeKey(id ID) string {
+	return strings.ToLower(id.String())
+}
+
+func decodeRecord(id ID, b []byte) (*Record, error) {
+	r := &Record{ID: id}
+	if len(b) == 0 {
+		return r, nil
+	}
+
+	if err := json.Unmarshal(b, r); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
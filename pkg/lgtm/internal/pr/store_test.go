@@ -0,0 +1,99 @@
+package pr_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/garukun/golgtm/pkg/lgtm/internal/pr"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestStores(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lgtm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := bolt.Open(filepath.Join(dir, "lgtm.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	boltStore, err := pr.NewBoltStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stores := map[string]pr.Store{
+		"memory": pr.NewMemoryStore(),
+		"bolt":   boltStore,
+	}
+
+	for name, s := range stores {
+		t.Logf("Testing %s store...", name)
+		testStore(t, s)
+
+		if err := s.Close(); err != nil {
+			t.Errorf("Unexpected error closing %s store: %v.", name, err)
+		}
+	}
+}
+
+func testStore(t *testing.T, s pr.Store) {
+	id := pr.ID{Owner: "garukun", Repo: "golgtm", Number: 1}
+
+	r, err := s.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(r, &pr.Record{ID: id}) {
+		t.Errorf("Expected an empty record instead of %+v.", r)
+	}
+
+	err = s.Update(id, func(r *pr.Record) error {
+		r.State = pr.Approved
+		r.HeadSHA = "abc"
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A failed update is discarded.
+	err = s.Update(id, func(r *pr.Record) error {
+		r.State = pr.InReview
+		return errors.New("glitch")
+	})
+	if err == nil {
+		t.Error("Expected the update error to be returned.")
+	}
+
+	// Records are looked up regardless of case.
+	if r, err = s.Get(pr.ID{Owner: "Garukun", Repo: "GoLGTM", Number: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if r.State != pr.Approved || r.HeadSHA != "abc" {
+		t.Errorf("Expected the approved record at abc instead of %+v.", r)
+	}
+
+	if r, err = s.Get(pr.ID{Owner: "garukun", Repo: "golgtm", Number: 2}); err != nil {
+		t.Fatal(err)
+	} else if r.State != pr.InReview {
+		t.Errorf("Expected another PR to be in review instead of %+v.", r)
+	}
+	if err := s.Delete(pr.ID{Owner: "Garukun", Repo: "GoLGTM", Number: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if r, err = s.Get(id); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(r, &pr.Record{ID: id}) {
+		t.Errorf("Expected an empty record once deleted instead of %+v.", r)
+	}
+}
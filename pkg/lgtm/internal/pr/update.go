@@ -1,10 +1,28 @@
 package pr
 
-import "github.com/google/go-github/github"
+import (
+	"fmt"
 
-type Update struct {
+	"github.com/google/go-github/github"
+)
+
+// ID identifies a PR across the served repositories.
+type ID struct {
+	Owner  string
+	Repo   string
 	Number int // Issue number, aka. PR number
-	State  State
+}
+
+func (id ID) String() string {
+	return fmt.Sprintf("%s/%s#%d", id.Owner, id.Repo, id.Number)
+}
+
+type Update struct {
+	ID
+	State State
+
+	// Description overrides the status description of the workflow context, if set.
+	Description string
 
 	// The fields belong should be set based on the context of the Github Event API, e.g., certain
 	// events does not directly pass an issue. It's up to the Updater's implementation on how to
@@ -21,3 +39,14 @@ const (
 	InReview State = iota
 	Approved
 )
+
+func (s State) String() string {
+	switch s {
+	case InReview:
+		return "in review"
+	case Approved:
+		return "approved"
+	default:
+		return fmt.Sprintf("State(%d)", s)
+	}
+}
@@ -1,76 +1,357 @@
 package pr
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"sync"
+	"time"
 
 	"github.com/garukun/golgtm/pkg/lgtm/config"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/codeowners"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/owners"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/repoconfig"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/teams"
 	"github.com/google/go-github/github"
 )
 
+// ErrClosed is returned when enqueuing updates to a closed Updater.
+var ErrClosed = errors.New("updater closed")
+
 type Updater struct {
 	*log.Logger
 
 	G      *github.Client
 	Config *config.Config
 
+	// Workflows provides the workflows configured by the repositories themselves, if set.
+	Workflows *repoconfig.Cache
+
+	// Owners resolves the OWNERS files for the workflows that require them.
+	Owners *owners.Resolver
+
+	// CodeOwners resolves the CODEOWNERS files for the workflows that require them.
+	CodeOwners *codeowners.Resolver
+
+	// Teams resolves the members of the teams required for approvals by the workflows.
+	Teams *teams.Resolver
+
+	// Store persists the review state of PRs; the state is kept in memory if not set.
+	Store Store
+
+	// Audit records every change of the review state of PRs and every override, if set.
+	Audit AuditLog
+
+	// Retry configures how failed GitHub API calls are retried before the update is kept as a dead
+	// letter.
+	Retry Retry
+
+	// Checks reports the review state through a check run of the Checks API instead of a commit
+	// status; only GitHub Apps may use the Checks API.
+	Checks bool
+
+	// Workers is the number of the workers applying the updates in parallel, 1 if not set. The
+	// updates of the same PR are applied in order by the same worker.
+	Workers int
+
+	// QueueSize is the most updates queued for every worker, 100 if not set; ErrQueueFull is
+	// returned when enqueuing more.
+	QueueSize int
+
+	initOnce  sync.Once
 	startOnce sync.Once
-	updatesCh chan Update
+	queues    []*queue
+	stopCh    chan struct{} // Closed when Close gives up on the queued updates.
+	doneCh    chan struct{} // Closed when no more updates will be applied.
+
+	mu      sync.RWMutex // Guards closing the queues.
+	closed  bool
+	started bool
+
+	storeOnce   sync.Once
+	deadLetters deadLetters
 }
 
-func (u *Updater) Updates() chan<- Update {
-	return u.updatesCh
+// Workflow method returns the workflow of the repository of the given PR.
+func (u *Updater) Workflow(id ID) *config.Workflow {
+	if u.Workflows != nil {
+		return u.Workflows.Workflow(id.Owner, id.Repo)
+	}
+
+	return u.Config.RepoWorkflow(id.Owner, id.Repo)
 }
 
-func (u *Updater) Start() {
-	const updateBuffer = 100
+// Record method returns the review state of the PR.
+func (u *Updater) Record(id ID) (*Record, error) {
+	return u.store().Get(id)
+}
+
+// Enqueue method records the update in the store as the outcome of the event, then queues the
+// update to be applied to GitHub. ErrClosed is returned once the Updater is closed, and
+// ErrQueueFull if the worker of the PR has too many updates queued; the update is not recorded
+// then, but a refused update is kept as a dead letter, so that an administrator may replay it. The
+// update is also recorded in the audit log if it changes the review state or is made by an
+// administrator.
+func (u *Updater) Enqueue(up Update, e Event) error {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	if u.closed {
+		return ErrClosed
+	}
+
+	u.init()
+
+	e.State = up.State
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	if up.PullRequest != nil && up.PullRequest.Head != nil && up.PullRequest.Head.SHA != nil {
+		e.HeadSHA = *up.PullRequest.Head.SHA
+	}
+
+	// Recording while queuing keeps the history in the order of the updates.
+	err := u.queue(up.ID).push(up, u.queueSize(), func() error {
+		var from State
+		err := u.store().Update(up.ID, func(r *Record) error {
+			from = r.State
+			r.record(e)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if u.Audit != nil && (from != e.State || e.Event == "admin") {
+			if err := u.Audit.Append(auditEntry(up.ID, from, e)); err != nil {
+				u.Printf("cannot audit %s as %s: %v", up.ID, e.State, err)
+			}
+		}
+
+		return nil
+	})
+	if err == ErrQueueFull {
+		u.Printf("queue full, kept update of %s as a dead letter", up.ID)
+		u.deadLetter(up, 0, err)
+	}
+
+	return err
+}
+
+func (u *Updater) store() Store {
+	u.storeOnce.Do(func() {
+		if u.Store == nil {
+			u.Store = NewMemoryStore()
+		}
+	})
+
+	return u.Store
+}
+
+// init method creates the queues of the workers; the updates queued before Start are applied once
+// started.
+func (u *Updater) init() {
+	u.initOnce.Do(func() {
+		n := u.Workers
+		if n <= 0 {
+			n = defaultWorkers
+		}
+
+		u.queues = make([]*queue, n)
+		for i := range u.queues {
+			u.queues[i] = newQueue()
+		}
+
+		u.stopCh = make(chan struct{})
+		u.doneCh = make(chan struct{})
+	})
+}
 
+// Start method starts the workers applying the queued updates to GitHub.
+func (u *Updater) Start() {
 	u.startOnce.Do(func() {
-		u.updatesCh = make(chan Update, updateBuffer)
+		u.mu.Lock()
+		defer u.mu.Unlock()
+
+		if u.closed {
+			return
+		}
+
+		u.init()
+		u.started = true
+
+		var wg sync.WaitGroup
+		for _, q := range u.queues {
+			wg.Add(1)
+			go func(q *queue) {
+				defer wg.Done()
+				u.run(q)
+			}(q)
+		}
+
+		go func() {
+			wg.Wait()
+			u.Print("No more updates, done!")
+			close(u.doneCh)
+		}()
 	})
+}
 
-	go func(updatesCh <-chan Update) {
-		gconf := u.Config.Github
-		w := u.Config.Workflow
+// run method applies the updates of the queue until the queue is closed.
+func (u *Updater) run(q *queue) {
+	for {
+		up, ok := q.pop()
+		if !ok {
+			return
+		}
 
-		var label, status string
+		select {
+		case <-u.stopCh:
+			u.dropped(up)
+			continue
+		default:
+		}
 
-		for up := range updatesCh {
-			switch up.State {
-			case InReview:
-				label = w.InReview.Label
-				status = "pending"
-			case Approved:
-				label = w.Approved.Label
-				status = "success"
-			}
+		if attempts, err := u.apply(up); err != nil {
+			u.Print(err)
+			u.deadLetter(up, attempts, err)
+		}
+	}
+}
 
-			u.Printf("appending label %s and status %s", label, status)
-			if up.Issue != nil {
-				labels := issue{up.Issue}.LabelsWithout(w.InReview.Label, w.Approved.Label)
-				labels = append(labels, label)
+// Close method stops accepting updates and waits for the queued updates to be applied until the
+// context is done; the updates not applied by then are logged and dropped.
+func (u *Updater) Close(ctx context.Context) error {
+	u.mu.Lock()
+	if u.closed {
+		u.mu.Unlock()
+		return nil
+	}
 
-				if _, _, err := u.G.Issues.ReplaceLabelsForIssue(gconf.Owner, gconf.Repo, up.Number, labels); err != nil {
-					u.Print(fmt.Errorf("cannot replace labels %v, %v", labels, err))
-				}
+	u.closed = true
+	if !u.started {
+		for _, q := range u.queues {
+			for _, up := range q.drain() {
+				u.dropped(up)
 			}
+		}
 
-			if up.PullRequest != nil {
-				ref := *up.PullRequest.Head.SHA
-				rs := &github.RepoStatus{
-					State:       &status,
-					TargetURL:   &w.Context.URL,
-					Context:     &w.Context.Name,
-					Description: &w.Context.Description,
-				}
-
-				if _, _, err := u.G.Repositories.CreateStatus(gconf.Owner, gconf.Repo, ref, rs); err != nil {
-					u.Print(fmt.Errorf("cannot create pending status, %s, %v", ref, err))
-				}
-			}
+		u.mu.Unlock()
+		return nil
+	}
+
+	for _, q := range u.queues {
+		q.close()
+	}
+	u.mu.Unlock()
+
+	select {
+	case <-u.doneCh:
+		return nil
+	case <-ctx.Done():
+	}
+
+	close(u.stopCh)
+	for _, q := range u.queues {
+		for _, up := range q.drain() {
+			u.dropped(up)
 		}
+	}
+
+	return ctx.Err()
+}
+
+func (u *Updater) dropped(up Update) {
+	u.Printf("shutting down, dropped update of %s", up.ID)
+}
+
+// apply method applies the update to the labels and the status of the PR, retrying transient
+// failures, and returns the attempts made by the failed API call, if any.
+func (u *Updater) apply(up Update) (int, error) {
+	w := u.Workflow(up.ID)
+
+	label, status := stateLabel(up.State, w), commitStatus(up.State)
+
+	u.Printf("appending label %s and status %s to %s", label, status, up.ID)
+	if up.Issue != nil {
+		labels := issue{up.Issue}.LabelsWithout(w.InReview.Label, w.Approved.Label)
+		labels = append(labels, label)
+
+		attempts, err := u.Retry.do(u.stopCh, func() error {
+			_, _, err := u.G.Issues.ReplaceLabelsForIssue(up.Owner, up.Repo, up.Number, labels)
+			return err
+		})
+		if err != nil {
+			return attempts, fmt.Errorf("cannot replace labels %v, %v", labels, err)
+		}
+	}
+
+	if up.PullRequest != nil && u.Checks {
+		attempts, err := u.Retry.do(u.stopCh, func() error {
+			return u.reportCheck(up, w)
+		})
+		if err != nil {
+			return attempts, fmt.Errorf("cannot report check run, %s, %v", *up.PullRequest.Head.SHA, err)
+		}
+	} else if up.PullRequest != nil {
+		ref := *up.PullRequest.Head.SHA
+		desc := statusDescription(up, w)
+		rs := &github.RepoStatus{
+			State:       &status,
+			TargetURL:   &w.Context.URL,
+			Context:     &w.Context.Name,
+			Description: &desc,
+		}
+
+		attempts, err := u.Retry.do(u.stopCh, func() error {
+			_, _, err := u.G.Repositories.CreateStatus(up.Owner, up.Repo, ref, rs)
+			return err
+		})
+		if err != nil {
+			return attempts, fmt.Errorf("cannot create %s status, %s, %v", status, ref, err)
+		}
+	}
+
+	return 0, nil
+}
+
+// stateLabel function returns the label of the review state in the workflow.
+func stateLabel(s State, w *config.Workflow) string {
+	switch s {
+	case InReview:
+		return w.InReview.Label
+	case Approved:
+		return w.Approved.Label
+	default:
+		return ""
+	}
+}
+
+// commitStatus function returns the commit status state of the review state.
+func commitStatus(s State) string {
+	switch s {
+	case InReview:
+		return "pending"
+	case Approved:
+		return "success"
+	default:
+		return ""
+	}
+}
+
+// statusDescription function returns the commit status description of the update.
+func statusDescription(up Update, w *config.Workflow) string {
+	desc := w.Context.Description
+	if len(up.Description) != 0 {
+		desc = up.Description
+	}
+
+	// GitHub limits the status description to 140 characters.
+	if len(desc) > 140 {
+		desc = desc[:137] + "..."
+	}
 
-		u.Print("No more updates, done!")
-	}(u.updatesCh)
+	return desc
 }
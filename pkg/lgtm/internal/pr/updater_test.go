@@ -0,0 +1,368 @@
+package pr_test
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/garukun/golgtm/pkg/lgtm/config"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/githubtest"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/pr"
+	"github.com/google/go-github/github"
+)
+
+func TestUpdaterClose(t *testing.T) {
+	u := &pr.Updater{
+		Logger: log.New(ioutil.Discard, "", 0),
+		Config: &config.Config{},
+	}
+	u.Start()
+
+	for i := 1; i <= 10; i++ {
+		id := pr.ID{Owner: "garukun", Repo: "golgtm", Number: i}
+		if err := u.Enqueue(pr.Update{ID: id, State: pr.Approved}, pr.Event{Event: "issue_comment"}); err != nil {
+			t.Fatalf("Unexpected error %v enqueuing update %d.", err, i)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Updates without an issue or a pull request make no GitHub calls, so they drain right away.
+	if err := u.Close(ctx); err != nil {
+		t.Errorf("Unexpected error %v closing the updater.", err)
+	}
+
+	id := pr.ID{Owner: "garukun", Repo: "golgtm", Number: 11}
+	if err := u.Enqueue(pr.Update{ID: id}, pr.Event{}); err != pr.ErrClosed {
+		t.Errorf("Expected %v enqueuing to a closed updater instead of %v.", pr.ErrClosed, err)
+	}
+
+	// Updates refused by a closed updater are not recorded.
+	if r, err := u.Record(id); err != nil {
+		t.Errorf("Unexpected error %v.", err)
+	} else if len(r.History) != 0 {
+		t.Errorf("Expected no history of %s instead of %v.", id, r.History)
+	}
+
+	if err := u.Close(ctx); err != nil {
+		t.Errorf("Unexpected error %v closing the updater again.", err)
+	}
+}
+
+func TestUpdaterQueueFull(t *testing.T) {
+	u := &pr.Updater{
+		Logger:    log.New(ioutil.Discard, "", 0),
+		Config:    &config.Config{},
+		QueueSize: 2,
+	}
+
+	// Nothing is applied before starting.
+	for i := 1; i <= 2; i++ {
+		id := pr.ID{Owner: "garukun", Repo: "golgtm", Number: i}
+		if err := u.Enqueue(pr.Update{ID: id, State: pr.Approved}, pr.Event{Event: "issue_comment"}); err != nil {
+			t.Fatalf("Unexpected error %v enqueuing update %d.", err, i)
+		}
+	}
+
+	id := pr.ID{Owner: "garukun", Repo: "golgtm", Number: 3}
+	if err := u.Enqueue(pr.Update{ID: id}, pr.Event{Event: "pull_request"}); err != pr.ErrQueueFull {
+		t.Errorf("Expected %v instead of %v.", pr.ErrQueueFull, err)
+	}
+
+	// Updates refused by a full queue are not recorded.
+	if r, err := u.Record(id); err != nil {
+		t.Errorf("Unexpected error %v.", err)
+	} else if len(r.History) != 0 {
+		t.Errorf("Expected no history of %s instead of %v.", id, r.History)
+	}
+
+	// But they are kept as dead letters.
+	if letters := u.DeadLetters(); len(letters) != 1 || letters[0].Update.ID != id {
+		t.Errorf("Expected a dead letter of %s instead of %v.", id, letters)
+	}
+
+	// Updates superseding queued updates still fit.
+	id.Number = 2
+	if err := u.Enqueue(pr.Update{ID: id}, pr.Event{Event: "pull_request"}); err != nil {
+		t.Errorf("Unexpected error %v.", err)
+	}
+
+	u.Start()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := u.Close(ctx); err != nil {
+		t.Errorf("Unexpected error %v closing the updater.", err)
+	}
+}
+
+func TestUpdaterWorkers(t *testing.T) {
+	var mu sync.Mutex
+	var applied []int // The PR numbers whose labels are replaced, in order.
+	release := make(chan struct{})
+
+	s := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		var number int
+		if _, err := fmt.Sscanf(req.URL.Path, "/repos/garukun/golgtm/issues/%d/labels", &number); err != nil {
+			// The statuses.
+			fmt.Fprint(resp, "{}")
+			return
+		}
+
+		if number == 1 {
+			// A slow GitHub API call.
+			<-release
+		}
+
+		mu.Lock()
+		applied = append(applied, number)
+		mu.Unlock()
+
+		fmt.Fprint(resp, "[]")
+	}))
+	defer s.Close()
+
+	g := github.NewClient(nil)
+	g.BaseURL, _ = url.Parse(s.URL + "/")
+
+	u := &pr.Updater{
+		Logger:  log.New(ioutil.Discard, "", 0),
+		G:       g,
+		Config:  &config.Config{},
+		Workers: 4,
+	}
+	u.Start()
+
+	slow := pr.ID{Owner: "garukun", Repo: "golgtm", Number: 1}
+	other := pr.ID{Owner: "garukun", Repo: "golgtm", Number: 2}
+	for u.SameWorker(slow, other) {
+		other.Number++
+	}
+
+	enqueue := func(id pr.ID, sha string) {
+		up := pr.Update{
+			ID:          id,
+			Issue:       &github.Issue{},
+			PullRequest: &github.PullRequest{Head: &github.PullRequestBranch{SHA: github.String(sha)}},
+		}
+		if err := u.Enqueue(up, pr.Event{Event: "issue_comment"}); err != nil {
+			t.Fatalf("Unexpected error %v enqueuing update of %s.", err, id)
+		}
+	}
+
+	// Updates of different head commits are not coalesced.
+	enqueue(slow, "a")
+	enqueue(slow, "b")
+	enqueue(other, "c")
+
+	// The other PR is updated while the slow one is stuck.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		n := len(applied)
+		mu.Unlock()
+
+		if n == 1 {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatal("Expected the other PR to be updated in parallel.")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := u.Close(ctx); err != nil {
+		t.Errorf("Unexpected error %v closing the updater.", err)
+	}
+
+	expected := []int{other.Number, 1, 1}
+	if !reflect.DeepEqual(applied, expected) {
+		t.Errorf("Expected updates %v instead of %v.", expected, applied)
+	}
+}
+
+func TestUpdaterCoalesce(t *testing.T) {
+	u := &pr.Updater{
+		Logger: log.New(ioutil.Discard, "", 0),
+		Config: &config.Config{},
+	}
+
+	pull := func(sha string) *github.PullRequest {
+		return &github.PullRequest{Head: &github.PullRequestBranch{SHA: github.String(sha)}}
+	}
+
+	one := pr.ID{Owner: "garukun", Repo: "golgtm", Number: 1}
+	two := pr.ID{Owner: "garukun", Repo: "golgtm", Number: 2}
+	issue := &github.Issue{Number: github.Int(1)}
+
+	updates := []pr.Update{
+		{ID: one, State: pr.InReview, Issue: issue, PullRequest: pull("a")},
+		{ID: one, State: pr.Approved, PullRequest: pull("a")},
+		{ID: two, State: pr.Approved},
+		{ID: one, State: pr.InReview, Issue: issue, PullRequest: pull("b")},
+		{ID: pr.ID{Owner: "Garukun", Repo: "golgtm", Number: 1}, State: pr.Approved, Issue: issue, PullRequest: pull("b")},
+		{ID: two, State: pr.InReview},
+	}
+
+	expected := []pr.Update{
+		{ID: one, State: pr.Approved, Issue: issue, PullRequest: pull("a")},
+		{ID: two, State: pr.InReview},
+		{ID: pr.ID{Owner: "Garukun", Repo: "golgtm", Number: 1}, State: pr.Approved, Issue: issue, PullRequest: pull("b")},
+	}
+
+	coalesced := expvar.Get("coalesced_updates").(*expvar.Int)
+	start := coalesced.Value()
+
+	for i, up := range updates {
+		t.Logf("Testing %d...", i)
+
+		if err := u.Enqueue(up, pr.Event{Event: "issue_comment"}); err != nil {
+			t.Errorf("Unexpected error %v.", err)
+		}
+	}
+
+	if queued := u.Queued(); !reflect.DeepEqual(queued, expected) {
+		t.Errorf("Expected queued updates %+v instead of %+v.", expected, queued)
+	}
+
+	if n := coalesced.Value() - start; n != 3 {
+		t.Errorf("Expected 3 coalesced updates instead of %d.", n)
+	}
+
+	// Every event is recorded all the same.
+	if r, _ := u.Record(one); len(r.History) != 4 || r.State != pr.Approved {
+		t.Errorf("Expected 4 events ending approved instead of %v.", r.History)
+	}
+}
+
+// blockingStore blocks the updates of the records until released.
+type blockingStore struct {
+	pr.Store
+	updating chan struct{}
+	release  chan struct{}
+}
+
+func (s *blockingStore) Update(id pr.ID, fn func(r *pr.Record) error) error {
+	s.updating <- struct{}{}
+	<-s.release
+	return s.Store.Update(id, fn)
+}
+
+func TestUpdaterRecordOutsideQueueLock(t *testing.T) {
+	store := &blockingStore{Store: pr.NewMemoryStore(), updating: make(chan struct{}), release: make(chan struct{})}
+	u := &pr.Updater{
+		Logger: log.New(ioutil.Discard, "", 0),
+		Config: &config.Config{},
+		Store:  store,
+	}
+
+	id := pr.ID{Owner: "garukun", Repo: "golgtm", Number: 1}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- u.Enqueue(pr.Update{ID: id, State: pr.Approved}, pr.Event{Event: "issue_comment"})
+	}()
+
+	<-store.updating
+
+	// The queue is not locked while recording.
+	queued := make(chan []pr.Update, 1)
+	go func() { queued <- u.Queued() }()
+
+	select {
+	case updates := <-queued:
+		if len(updates) != 0 {
+			t.Errorf("Expected no updates queued before recording instead of %+v.", updates)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the queue not to be locked while recording.")
+	}
+
+	close(store.release)
+	if err := <-errCh; err != nil {
+		t.Errorf("Unexpected error %v.", err)
+	}
+
+	if updates := u.Queued(); len(updates) != 1 {
+		t.Errorf("Expected 1 update queued instead of %+v.", updates)
+	}
+}
+
+func TestUpdaterReplay(t *testing.T) {
+	s := githubtest.NewServer()
+	defer s.Close()
+
+	s.AddPull("garukun", "golgtm", githubtest.Pull{Number: 1, Author: "neo", HeadSHA: "head", Labels: []string{"bug"}})
+	s.Inject(githubtest.ServerError("PUT", "/repos/garukun/golgtm/issues/1/labels", 1))
+
+	g := github.NewClient(nil)
+	g.BaseURL, _ = url.Parse(s.BaseURL())
+
+	conf := &config.Config{}
+	conf.Workflow.InReview.Label = "Needs Review"
+	conf.Workflow.Approved.Label = "Ready"
+	conf.Workflow.Approved.Triggers = config.Triggers{{Phrase: "lgtm", Count: 1}}
+
+	u := &pr.Updater{Logger: log.New(ioutil.Discard, "", 0), G: g, Config: conf}
+	u.Start()
+
+	id := pr.ID{Owner: "garukun", Repo: "golgtm", Number: 1}
+	issue := &github.Issue{Number: github.Int(1), Labels: []github.Label{{Name: github.String("bug")}}}
+	if err := u.Enqueue(pr.Update{ID: id, State: pr.InReview, Issue: issue}, pr.Event{Event: "issue_comment"}); err != nil {
+		t.Fatalf("Unexpected error %v.", err)
+	}
+
+	letters := u.DeadLetters()
+	for deadline := time.Now().Add(5 * time.Second); len(letters) == 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		letters = u.DeadLetters()
+	}
+
+	if len(letters) != 1 {
+		t.Fatalf("Expected 1 dead letter instead of %v.", letters)
+	}
+
+	// The PR moves on meanwhile.
+	s.AddPull("garukun", "golgtm", githubtest.Pull{Number: 1, Author: "neo", HeadSHA: "head", Labels: []string{"bug", "urgent"}})
+	u.Tally(id, "lgtm", "trinity")
+
+	if err := u.Replay(letters[0].ID+1, "admin"); err != pr.ErrNoDeadLetter {
+		t.Errorf("Expected %v instead of %v.", pr.ErrNoDeadLetter, err)
+	}
+
+	if err := u.Replay(letters[0].ID, "admin"); err != nil {
+		t.Errorf("Unexpected error %v.", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	u.Close(ctx)
+	cancel()
+
+	// The PR is evaluated again rather than given the labels of the dead letter.
+	if labels := s.Labels("garukun", "golgtm", 1); !reflect.DeepEqual(labels, []string{"bug", "urgent", "Ready"}) {
+		t.Errorf("Expected labels [bug urgent Ready] instead of %v.", labels)
+	}
+
+	if letters := u.DeadLetters(); len(letters) != 0 {
+		t.Errorf("Expected no dead letters instead of %v.", letters)
+	}
+
+	if r, _ := u.Record(id); len(r.History) == 0 || r.History[len(r.History)-1].Action != "replay" {
+		t.Errorf("Expected the replay to be recorded instead of %v.", r.History)
+	}
+}
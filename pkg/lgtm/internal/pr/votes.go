@@ -0,0 +1,48 @@
+package pr
+
+// ReviewApproval is the phrase under which approving GitHub reviews are tallied; a review approval
+// counts towards every approved trigger of the workflow.
+const ReviewApproval = "@review:approved"
+
+// Tally method records that the given user has said the trigger phrase on the PR and returns the
+// number of distinct users who have said the phrase so far.
+func (u *Updater) Tally(id ID, phrase, user string) (int, error) {
+	var n int
+	err := u.store().Update(id, func(r *Record) error {
+		n = r.vote(phrase, user)
+		return nil
+	})
+
+	return n, err
+}
+
+// Reset method forgets all the votes on the PR, e.g., when the PR goes back to review.
+func (u *Updater) Reset(id ID) error {
+	return u.store().Update(id, func(r *Record) error {
+		r.Votes = nil
+		return nil
+	})
+}
+
+// Remind method records the reminder posted on the PR, so that the reminders survive restarts.
+func (u *Updater) Remind(id ID, r Reminder) error {
+	return u.store().Update(id, func(rec *Record) error {
+		rec.Reminded = &r
+		return nil
+	})
+}
+
+// Forget method deletes the record of the PR, e.g., once the PR is closed or merged, so that the
+// store does not grow forever; the PR starts over in review if reopened.
+func (u *Updater) Forget(id ID) error {
+	return u.store().Delete(id)
+}
+
+// Revoke method forgets that the given user has said the trigger phrase on the PR, e.g., when the
+// approving review of the user is dismissed.
+func (u *Updater) Revoke(id ID, phrase, user string) error {
+	return u.store().Update(id, func(r *Record) error {
+		r.unvote(phrase, user)
+		return nil
+	})
+}
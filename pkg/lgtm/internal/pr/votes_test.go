@@ -0,0 +1,43 @@
+package pr_test
+
+import (
+	"testing"
+
+	"github.com/garukun/golgtm/pkg/lgtm/internal/pr"
+)
+
+func TestTally(t *testing.T) {
+	tests := []struct {
+		id     pr.ID
+		phrase string
+		user   string
+		reset  bool
+		count  int
+	}{
+		{id: pr.ID{Owner: "garukun", Repo: "golgtm", Number: 1}, phrase: "lgtm", user: "neo", count: 1},
+		// Same user only counts once, regardless of case.
+		{id: pr.ID{Owner: "garukun", Repo: "golgtm", Number: 1}, phrase: "lgtm", user: "Neo", count: 1},
+		{id: pr.ID{Owner: "garukun", Repo: "golgtm", Number: 1}, phrase: "lgtm", user: "trinity", count: 2},
+		// Different phrases and PRs are counted separately.
+		{id: pr.ID{Owner: "garukun", Repo: "go-lgtm", Number: 1}, phrase: "lgtm", user: "neo", count: 1},
+		{id: pr.ID{Owner: "garukun", Repo: "golgtm", Number: 1}, phrase: ":+1:", user: "morpheus", count: 1},
+		{id: pr.ID{Owner: "garukun", Repo: "golgtm", Number: 2}, phrase: "lgtm", user: "neo", count: 1},
+		// Reset forgets all votes on the PR.
+		{id: pr.ID{Owner: "garukun", Repo: "golgtm", Number: 1}, phrase: "lgtm", user: "morpheus", reset: true, count: 1},
+		{id: pr.ID{Owner: "garukun", Repo: "golgtm", Number: 2}, phrase: "lgtm", user: "trinity", count: 2},
+	}
+
+	u := &pr.Updater{}
+	for i, test := range tests {
+		t.Logf("Testing %d...", i)
+		if test.reset {
+			u.Reset(test.id)
+		}
+
+		if count, err := u.Tally(test.id, test.phrase, test.user); err != nil {
+			t.Errorf("Unexpected error %v.", err)
+		} else if count != test.count {
+			t.Errorf("Expected %d vote(s) for %s on %s instead of %d.", test.count, test.phrase, test.id, count)
+		}
+	}
+}
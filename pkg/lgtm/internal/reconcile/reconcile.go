@@ -0,0 +1,84 @@
+/*
+Package reconcile keeps the labels and the statuses of the open PRs in sync with their review
+state, which the webhook events alone may fail to do, e.g., after a missed webhook delivery, a
+crash with updates still queued or a manual label edit.
+*/
+package reconcile
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/garukun/golgtm/pkg/lgtm/internal/clock"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/pr"
+)
+
+// Scheduler reconciles every open PR on start and every interval afterwards; see
+// pr.Updater.Reconcile.
+type Scheduler struct {
+	*pr.Updater
+
+	// Interval is the time between two reconciliations.
+	Interval time.Duration
+
+	// Clock is the system clock if not set.
+	Clock clock.Clock
+
+	startOnce sync.Once
+	stopCh    chan struct{}
+	doneCh    chan struct{}
+}
+
+// Start method reconciles the PRs in the background until Close.
+func (s *Scheduler) Start() {
+	s.startOnce.Do(func() {
+		s.stopCh = make(chan struct{})
+		s.doneCh = make(chan struct{})
+
+		go func() {
+			defer close(s.doneCh)
+
+			s.ReconcileAll(s.stopCh)
+			for {
+				select {
+				case <-s.stopCh:
+					return
+				case <-s.clock().After(s.Interval):
+					s.ReconcileAll(s.stopCh)
+				}
+			}
+		}()
+	})
+}
+
+// Close method stops reconciling the PRs, and waits for the PR being reconciled to finish until the
+// context is done.
+func (s *Scheduler) Close(ctx context.Context) error {
+	s.startOnce.Do(func() {})
+	if s.stopCh == nil {
+		// Never started.
+		return nil
+	}
+
+	select {
+	case <-s.stopCh:
+	default:
+		close(s.stopCh)
+	}
+
+	select {
+	case <-s.doneCh:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Scheduler) clock() clock.Clock {
+	if s.Clock == nil {
+		return clock.System
+	}
+
+	return s.Clock
+}
@@ -0,0 +1,280 @@
+/*
+Package reminder reminds the reviewers of the PRs that sit in review without any activity.
+
+The Scheduler periodically lists the open PRs labeled in review of every configured repository.
+Once a PR has been idle for long enough, a comment mentioning its requested reviewers is posted,
+which also mentions the escalation contacts once the PR has been idle for even longer. Reminders
+on the same PR are at least an interval apart, across restarts too, since the last reminder is kept
+in the record of the PR.
+*/
+package reminder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/garukun/golgtm/pkg/lgtm/internal/clock"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/pr"
+	"github.com/google/go-github/github"
+)
+
+// reviewRequestsAccept is the media type of the review requests API, which is in preview.
+const reviewRequestsAccept = "application/vnd.github.black-cat-preview+json"
+
+// activitySlack is how long after a reminder an update of the PR is still considered to be the
+// reminder itself rather than any activity.
+const activitySlack = time.Minute
+
+// Scheduler posts reminders on the idle PRs in review; see package reminder.
+type Scheduler struct {
+	*pr.Updater
+
+	// Clock is the system clock if not set.
+	Clock clock.Clock
+
+	startOnce sync.Once
+	stopCh    chan struct{}
+	doneCh    chan struct{}
+}
+
+// Start method checks the PRs for reminders every poll interval in the background until Close.
+func (s *Scheduler) Start() {
+	s.startOnce.Do(func() {
+		s.stopCh = make(chan struct{})
+		s.doneCh = make(chan struct{})
+
+		go func() {
+			defer close(s.doneCh)
+			for {
+				select {
+				case <-s.stopCh:
+					return
+				case <-s.clock().After(s.Config.Reminders.PollInterval):
+					s.Run(s.stopCh)
+				}
+			}
+		}()
+	})
+}
+
+// Close method stops checking the PRs for reminders, and waits for the PR being checked to finish
+// until the context is done.
+func (s *Scheduler) Close(ctx context.Context) error {
+	s.startOnce.Do(func() {})
+	if s.stopCh == nil {
+		// Never started.
+		return nil
+	}
+
+	select {
+	case <-s.stopCh:
+	default:
+		close(s.stopCh)
+	}
+
+	select {
+	case <-s.doneCh:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Run method checks the PRs in review of every configured repository once, and posts the reminders
+// due, until the stop channel is closed, if any.
+func (s *Scheduler) Run(stop <-chan struct{}) {
+	for _, r := range s.Config.Github.Repos {
+		if stopped(stop) {
+			return
+		}
+
+		if err := s.remindRepo(r.Owner, r.Name, stop); err != nil {
+			log.Printf("cannot remind reviewers of %s: %v", r.FullName(), err)
+		}
+	}
+}
+
+func (s *Scheduler) remindRepo(owner, repo string, stop <-chan struct{}) error {
+	w := s.Workflow(pr.ID{Owner: owner, Repo: repo})
+
+	opt := &github.IssueListByRepoOptions{
+		State:       "open",
+		Labels:      []string{w.InReview.Label},
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		issues, resp, err := s.G.Issues.ListByRepo(owner, repo, opt)
+		if err != nil {
+			return err
+		}
+
+		for _, issue := range issues {
+			if issue.PullRequestLinks == nil || issue.UpdatedAt == nil {
+				continue
+			}
+
+			if stopped(stop) {
+				return nil
+			}
+
+			id := pr.ID{Owner: owner, Repo: repo, Number: *issue.Number}
+			if err := s.remind(id, *issue.UpdatedAt); err != nil {
+				log.Printf("cannot remind reviewers of %s: %v", id, err)
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		opt.Page = resp.NextPage
+	}
+
+	return nil
+}
+
+// remind method posts a reminder on the PR last updated at the given time, if due.
+func (s *Scheduler) remind(id pr.ID, updated time.Time) error {
+	conf := s.Config.Reminders
+	now := s.clock().Now()
+
+	r, err := s.Record(id)
+	if err != nil {
+		return err
+	}
+
+	// The reminders themselves update the PR, which is not any activity.
+	last := r.Reminded
+	idleSince := updated
+	if last != nil && !updated.After(last.Posted.Add(activitySlack)) {
+		idleSince = last.IdleSince
+	}
+
+	idle := now.Sub(idleSince)
+	if idle < conf.IdleAfter || last != nil && now.Sub(last.At) < conf.Interval {
+		return nil
+	}
+
+	escalated := conf.EscalateAfter > 0 && idle >= conf.EscalateAfter
+
+	reviewers, err := s.requestedReviewers(id)
+	if err != nil {
+		return err
+	}
+
+	body := message(reviewers, idle, escalated, conf.EscalateTo)
+	comment, _, err := s.G.Issues.CreateComment(id.Owner, id.Repo, id.Number, &github.IssueComment{Body: &body})
+	if err != nil {
+		return err
+	}
+
+	reminded := pr.Reminder{IdleSince: idleSince, Posted: now, At: now}
+	if comment != nil && comment.CreatedAt != nil {
+		reminded.Posted = *comment.CreatedAt
+	}
+
+	if err := s.Remind(id, reminded); err != nil {
+		return err
+	}
+
+	log.Printf("Reminded reviewers of %s idle for %s.", id, idle)
+	return nil
+}
+
+// requestedReviewers method returns the mentions of the users and the teams requested to review the
+// PR, e.g., @neo and @garukun/platform-team.
+func (s *Scheduler) requestedReviewers(id pr.ID) ([]string, error) {
+	req, err := s.G.NewRequest("GET", fmt.Sprintf("repos/%s/%s/pulls/%d/requested_reviewers", id.Owner, id.Repo, id.Number), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", reviewRequestsAccept)
+
+	requested := &struct {
+		Users []github.User `json:"users"`
+		Teams []github.Team `json:"teams"`
+	}{}
+	if _, err := s.G.Do(req, requested); err != nil {
+		return nil, err
+	}
+
+	var mentions []string
+	for _, u := range requested.Users {
+		if u.Login != nil {
+			mentions = append(mentions, "@"+*u.Login)
+		}
+	}
+
+	for _, t := range requested.Teams {
+		if t.Slug != nil {
+			mentions = append(mentions, "@"+id.Owner+"/"+*t.Slug)
+		}
+	}
+
+	return mentions, nil
+}
+
+// message function returns the reminder comment.
+func message(reviewers []string, idle time.Duration, escalated bool, escalateTo []string) string {
+	b := &bytes.Buffer{}
+
+	mentions := reviewers
+	if escalated {
+		for _, m := range escalateTo {
+			if !strings.HasPrefix(m, "@") {
+				m = "@" + m
+			}
+
+			mentions = append(mentions, m)
+		}
+	}
+
+	if len(mentions) != 0 {
+		fmt.Fprintf(b, "%s: ", strings.Join(mentions, " "))
+	}
+
+	fmt.Fprintf(b, "This PR has been waiting for review without any activity for %s.", duration(idle))
+	if escalated {
+		b.WriteString(" Escalating as it has been idle for too long.")
+	}
+
+	return b.String()
+}
+
+// duration function returns the rough duration in days or hours, e.g., 3 days.
+func duration(d time.Duration) string {
+	n, unit := int(d/time.Hour), "hour"
+	if n >= 48 {
+		n, unit = n/24, "day"
+	}
+
+	if n != 1 {
+		unit += "s"
+	}
+
+	return fmt.Sprintf("%d %s", n, unit)
+}
+
+func (s *Scheduler) clock() clock.Clock {
+	if s.Clock == nil {
+		return clock.System
+	}
+
+	return s.Clock
+}
+
+// stopped function returns whether the stop channel is closed; a nil channel is never closed.
+func stopped(stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
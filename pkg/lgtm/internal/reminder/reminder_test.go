@@ -0,0 +1,183 @@
+package reminder_test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/garukun/golgtm/pkg/lgtm/config"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/clock/clocktest"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/githubtest"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/pr"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/reminder"
+	"github.com/google/go-github/github"
+)
+
+func newScheduler(s *githubtest.Server, clock *clocktest.Clock) *reminder.Scheduler {
+	g := github.NewClient(nil)
+	g.BaseURL, _ = url.Parse(s.BaseURL())
+
+	conf := &config.Config{}
+	conf.Github.Repos = []config.Repo{{Owner: "garukun", Name: "golgtm"}}
+	conf.Workflow.InReview.Label = "Needs Review"
+	conf.Reminders.IdleAfter = 48 * time.Hour
+	conf.Reminders.EscalateAfter = 96 * time.Hour
+	conf.Reminders.EscalateTo = []string{"garukun/leads"}
+	conf.Reminders.Interval = 24 * time.Hour
+	conf.Reminders.PollInterval = time.Hour
+
+	return &reminder.Scheduler{
+		Updater: &pr.Updater{G: g, Config: conf},
+		Clock:   clock,
+	}
+}
+
+func comments(s *githubtest.Server, number int) []string {
+	p, _ := s.Pull("garukun", "golgtm", number)
+
+	var bodies []string
+	for _, c := range p.Comments {
+		bodies = append(bodies, c.Body)
+	}
+
+	return bodies
+}
+
+func TestRun(t *testing.T) {
+	s := githubtest.NewServer()
+	defer s.Close()
+
+	start := time.Now()
+	s.AddPull("garukun", "golgtm", githubtest.Pull{
+		Number:    1,
+		Author:    "tank",
+		Labels:    []string{"Needs Review"},
+		Reviewers: []string{"neo", "trinity"},
+		Updated:   start,
+	})
+	s.AddPull("garukun", "golgtm", githubtest.Pull{Number: 2, Labels: []string{"Ready"}, Updated: start})
+
+	clock := clocktest.New(start)
+	sch := newScheduler(s, clock)
+
+	tests := []struct {
+		after    time.Duration // Since the start.
+		activity bool          // Whether the PR is updated right before.
+		expected string        // The reminder posted, if any.
+	}{
+		{after: 47 * time.Hour},
+		{after: 48 * time.Hour, expected: "@neo @trinity: This PR has been waiting for review without any activity for 2 days."},
+		// Reminders are an interval apart.
+		{after: 60 * time.Hour},
+		{after: 72 * time.Hour, expected: "@neo @trinity: This PR has been waiting for review without any activity for 3 days."},
+		{after: 96 * time.Hour, expected: "@neo @trinity @garukun/leads: This PR has been waiting for review without any activity for 4 days. Escalating as it has been idle for too long."},
+		// Any activity resets the idle time, but not the interval.
+		{after: 100 * time.Hour, activity: true},
+		{after: 147 * time.Hour},
+		{after: 148 * time.Hour, expected: "@neo @trinity: This PR has been waiting for review without any activity for 2 days."},
+	}
+
+	n := 0
+	for i, test := range tests {
+		t.Logf("Testing %d...", i)
+
+		now := start.Add(test.after)
+		if test.activity {
+			p, _ := s.Pull("garukun", "golgtm", 1)
+			p.Updated = now
+			s.AddPull("garukun", "golgtm", p)
+		}
+
+		clock.Set(now)
+		sch.Run(nil)
+
+		bodies := comments(s, 1)
+		switch {
+		case len(test.expected) == 0 && len(bodies) != n:
+			t.Errorf("Expected no reminder instead of %q.", bodies[len(bodies)-1])
+		case len(test.expected) != 0 && len(bodies) != n+1:
+			t.Errorf("Expected reminder %q instead of none.", test.expected)
+		case len(test.expected) != 0 && bodies[n] != test.expected:
+			t.Errorf("Expected reminder %q instead of %q.", test.expected, bodies[n])
+		}
+
+		n = len(bodies)
+	}
+
+	// PRs not in review are never reminded.
+	if bodies := comments(s, 2); len(bodies) != 0 {
+		t.Errorf("Expected no reminders on the approved PR instead of %v.", bodies)
+	}
+}
+
+func TestRunRestarted(t *testing.T) {
+	s := githubtest.NewServer()
+	defer s.Close()
+
+	start := time.Now()
+	s.AddPull("garukun", "golgtm", githubtest.Pull{Number: 1, Labels: []string{"Needs Review"}, Updated: start})
+
+	clock := clocktest.New(start.Add(48 * time.Hour))
+	sch := newScheduler(s, clock)
+	sch.Run(nil)
+
+	// A new Scheduler on the same PR store still keeps the reminders an interval apart.
+	sch = &reminder.Scheduler{Updater: sch.Updater, Clock: clock}
+	for i, after := range []time.Duration{60 * time.Hour, 72 * time.Hour} {
+		t.Logf("Testing %d...", i)
+
+		clock.Set(start.Add(after))
+		sch.Run(nil)
+
+		if bodies := comments(s, 1); len(bodies) != i+1 {
+			t.Errorf("Expected %d reminders instead of %v.", i+1, bodies)
+		}
+	}
+}
+
+func TestStart(t *testing.T) {
+	s := githubtest.NewServer()
+	defer s.Close()
+
+	start := time.Now()
+	s.AddPull("garukun", "golgtm", githubtest.Pull{Number: 1, Labels: []string{"Needs Review"}, Updated: start})
+
+	clock := clocktest.New(start.Add(48 * time.Hour))
+	clock.Ticks = make(chan time.Time)
+	sch := newScheduler(s, clock)
+	sch.Start()
+
+	// The second tick is only received once the check of the first tick is done.
+	clock.Ticks <- clock.Now()
+	clock.Ticks <- clock.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := sch.Close(ctx); err != nil {
+		t.Errorf("Unexpected error %v.", err)
+	}
+
+	expected := "This PR has been waiting for review without any activity for 2 days."
+	if bodies := comments(s, 1); len(bodies) != 1 || bodies[0] != expected {
+		t.Errorf("Expected reminder %q instead of %v.", expected, bodies)
+	}
+}
+
+func TestRunStopped(t *testing.T) {
+	s := githubtest.NewServer()
+	defer s.Close()
+
+	start := time.Now()
+	s.AddPull("garukun", "golgtm", githubtest.Pull{Number: 1, Labels: []string{"Needs Review"}, Updated: start})
+
+	sch := newScheduler(s, clocktest.New(start.Add(48 * time.Hour)))
+
+	stop := make(chan struct{})
+	close(stop)
+	sch.Run(stop)
+
+	if bodies := comments(s, 1); len(bodies) != 0 {
+		t.Errorf("Expected no reminders once stopped instead of %v.", bodies)
+	}
+}
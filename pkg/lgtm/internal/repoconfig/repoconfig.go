@@ -0,0 +1,130 @@
+/*
+Package repoconfig provides the workflows that repositories configure for themselves in a file on
+their default branch.
+*/
+package repoconfig
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/garukun/golgtm/pkg/lgtm/config"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/clock"
+	"github.com/google/go-github/github"
+)
+
+// File is the path of the workflow file in a repository. The file has the same format as the
+// workflow section of the service configuration file and only needs to list the settings it
+// overrides.
+const File = ".lgtm.yaml"
+
+// DefaultFailureTTL is how long a failure to read the File is cached by default.
+const DefaultFailureTTL = time.Minute
+
+// Cache keeps the workflow read from the File of every repository, which overrides the configured
+// workflow of the repository. A repository without the File uses the configured workflow.
+//
+// The cached workflow of a repository is refreshed when its default branch is pushed to. A File
+// that cannot be read or parsed, e.g., upon a server error or a timeout, is not read again for the
+// FailureTTL; the last good workflow of the repository is used meanwhile, if any.
+type Cache struct {
+	G          *github.Client
+	Config     *config.Config
+	FailureTTL time.Duration // DefaultFailureTTL if not positive.
+
+	// Clock is the system clock if not set.
+	Clock clock.Clock
+
+	mu        sync.RWMutex
+	workflows map[string]entry // key: lower case owner/repo.
+}
+
+type entry struct {
+	workflow *config.Workflow // The last good workflow; nil for no File.
+	failed   time.Time        // When the File last failed to be read; zero if read since.
+}
+
+// Workflow method returns the workflow of the repository; the File is read upon the first use.
+func (c *Cache) Workflow(owner, repo string) *config.Workflow {
+	c.mu.RLock()
+	e, ok := c.workflows[key(owner, repo)]
+	c.mu.RUnlock()
+
+	if !ok || !e.failed.IsZero() && c.clock().Now().Sub(e.failed) >= c.failureTTL() {
+		if w, err := c.Refresh(owner, repo); err != nil {
+			log.Printf("cannot read %s of %s/%s: %v", File, owner, repo, err)
+		} else {
+			e.workflow = w
+		}
+	}
+
+	if e.workflow == nil {
+		return c.Config.RepoWorkflow(owner, repo)
+	}
+
+	return e.workflow
+}
+
+// Refresh method reads the File of the repository again. A missing File is cached as such, while
+// the failure is cached along with the last good workflow if the File cannot be read or parsed.
+func (c *Cache) Refresh(owner, repo string) (*config.Workflow, error) {
+	w, err := c.read(owner, repo)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.workflows == nil {
+		c.workflows = make(map[string]entry)
+	}
+
+	k := key(owner, repo)
+	if err != nil {
+		e := c.workflows[k]
+		e.failed = c.clock().Now()
+		c.workflows[k] = e
+		return nil, err
+	}
+
+	c.workflows[k] = entry{workflow: w}
+	return w, nil
+}
+
+func (c *Cache) read(owner, repo string) (*config.Workflow, error) {
+	// Without a ref, the File is read from the default branch.
+	f, _, resp, err := c.G.Repositories.GetContents(owner, repo, File, nil)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	b, err := f.Decode()
+	if err != nil {
+		return nil, err
+	}
+
+	return config.ParseWorkflow(b, c.Config.RepoWorkflow(owner, repo))
+}
+
+func (c *Cache) failureTTL() time.Duration {
+	if c.FailureTTL > 0 {
+		return c.FailureTTL
+	}
+
+	return DefaultFailureTTL
+}
+
+func (c *Cache) clock() clock.Clock {
+	if c.Clock == nil {
+		return clock.System
+	}
+
+	return c.Clock
+}
+
+func key(owner, repo string) string {
+	return strings.ToLower(owner + "/" + repo)
+}
@@ -0,0 +1,72 @@
+package repoconfig_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/garukun/golgtm/pkg/lgtm/config"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/clock/clocktest"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/githubtest"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/repoconfig"
+	"github.com/google/go-github/github"
+)
+
+func TestCacheFailure(t *testing.T) {
+	s := githubtest.NewServer()
+	defer s.Close()
+
+	g := github.NewClient(nil)
+	g.BaseURL, _ = url.Parse(s.BaseURL())
+
+	conf := &config.Config{}
+	conf.Workflow.Approved.Label = "Ready"
+
+	clock := clocktest.New(time.Now())
+	c := &repoconfig.Cache{G: g, Config: conf, FailureTTL: time.Minute, Clock: clock}
+
+	tests := []struct {
+		content string        // The new content of the File, if any.
+		fail    bool          // Whether reading the File fails.
+		refresh bool          // Whether the File is refreshed, as upon a push, before use.
+		elapsed time.Duration // The time passed since the previous test.
+		label   string
+	}{
+		// The configured workflow is used until the File can be read.
+		{content: "approved:\n  label: LGTM\n", fail: true, label: "Ready"},
+		{elapsed: 30 * time.Second, label: "Ready"},
+		{elapsed: 30 * time.Second, label: "LGTM"},
+		// The last good workflow is used until the File can be read again.
+		{content: "approved:\n  label: Ship It\n", fail: true, refresh: true, label: "LGTM"},
+		{elapsed: 30 * time.Second, label: "LGTM"},
+		{elapsed: 30 * time.Second, label: "Ship It"},
+		// Failures to parse the File are cached all the same.
+		{content: "approved: [", refresh: true, label: "Ship It"},
+		{content: "approved:\n  label: Done\n", elapsed: 30 * time.Second, label: "Ship It"},
+		{elapsed: time.Minute, label: "Done"},
+	}
+
+	for i, test := range tests {
+		t.Logf("Testing %d...", i)
+
+		if len(test.content) != 0 {
+			s.SetFile("garukun", "golgtm", "master", repoconfig.File, test.content)
+		}
+
+		if test.fail {
+			s.Inject(githubtest.ServerError(http.MethodGet, "/repos/garukun/golgtm/contents/", 1))
+		}
+
+		clock.Add(test.elapsed)
+		if test.refresh {
+			if _, err := c.Refresh("garukun", "golgtm"); err == nil {
+				t.Error("Expected the refresh to fail.")
+			}
+		}
+
+		if label := c.Workflow("garukun", "golgtm").Approved.Label; label != test.label {
+			t.Errorf("Expected label %q instead of %q.", test.label, label)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+/*
+Package teams resolves the members of GitHub teams, e.g., to require approvals from a team.
+*/
+package teams
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/garukun/golgtm/pkg/lgtm/internal/clock"
+	"github.com/google/go-github/github"
+)
+
+// DefaultTTL is how long the members of a team are cached by default.
+const DefaultTTL = 10 * time.Minute
+
+// Resolver resolves the members of teams via the Teams API and caches them for a while, so that
+// membership changes take effect eventually.
+type Resolver struct {
+	G   *github.Client
+	TTL time.Duration // DefaultTTL if not set.
+
+	// Clock is the system clock if not set.
+	Clock clock.Clock
+
+	mu    sync.Mutex       // Guards teams, but is not held while listing the members.
+	teams map[string]*team // key: lower case org/slug.
+}
+
+type team struct {
+	done    chan struct{}   // Closed once the members are listed, or failed to.
+	members map[string]bool // key: lower case login.
+	err     error
+	expires time.Time
+}
+
+// IsMember method returns whether the user is a member of the team of the organization.
+func (r *Resolver) IsMember(org, slug, user string) (bool, error) {
+	members, err := r.Members(org, slug)
+	if err != nil {
+		return false, err
+	}
+
+	return members[strings.ToLower(user)], nil
+}
+
+// Members method returns the lower case logins of the members of the team of the organization.
+// Concurrent calls for the same team share a single listing of the members.
+func (r *Resolver) Members(org, slug string) (map[string]bool, error) {
+	key := strings.ToLower(org + "/" + slug)
+
+	r.mu.Lock()
+	if t, ok := r.teams[key]; ok {
+		select {
+		case <-t.done:
+			if r.clock().Now().Before(t.expires) {
+				r.mu.Unlock()
+				return t.members, nil
+			}
+		default:
+			// Being listed by another call.
+			r.mu.Unlock()
+			<-t.done
+			return t.members, t.err
+		}
+	}
+
+	t := &team{done: make(chan struct{})}
+	if r.teams == nil {
+		r.teams = make(map[string]*team)
+	}
+
+	r.teams[key] = t
+	r.mu.Unlock()
+
+	members, err := r.listMembers(org, slug)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err != nil {
+		t.err = fmt.Errorf("cannot list members of %s/%s: %v", org, slug, err)
+		if r.teams[key] == t {
+			// The next call lists the members again.
+			delete(r.teams, key)
+		}
+	} else {
+		ttl := r.TTL
+		if ttl <= 0 {
+			ttl = DefaultTTL
+		}
+
+		t.members = members
+		t.expires = r.clock().Now().Add(ttl)
+	}
+
+	close(t.done)
+	return t.members, t.err
+}
+
+// listMembers method lists the members of the team through the endpoint scoped by the
+// organization, so that the request is authenticated as the installation of the organization when
+// running as a GitHub App. The GitHub client only supports listing the members by the team ID.
+func (r *Resolver) listMembers(org, slug string) (map[string]bool, error) {
+	members := make(map[string]bool)
+
+	path := fmt.Sprintf("orgs/%s/teams/%s/members?per_page=100", org, slug)
+	for len(path) != 0 {
+		req, err := r.G.NewRequest("GET", path, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var users []*github.User
+		resp, err := r.G.Do(req, &users)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, u := range users {
+			if u.Login != nil {
+				members[strings.ToLower(*u.Login)] = true
+			}
+		}
+
+		path = ""
+		if resp.NextPage != 0 {
+			path = fmt.Sprintf("orgs/%s/teams/%s/members?per_page=100&page=%d", org, slug, resp.NextPage)
+		}
+	}
+
+	return members, nil
+}
+
+func (r *Resolver) clock() clock.Clock {
+	if r.Clock == nil {
+		return clock.System
+	}
+
+	return r.Clock
+}
@@ -0,0 +1,134 @@
+package teams_test
+
+import (
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/garukun/golgtm/pkg/lgtm/internal/clock/clocktest"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/githubtest"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/teams"
+	"github.com/google/go-github/github"
+)
+
+func newResolver(t *testing.T, s *githubtest.Server, clock *clocktest.Clock) *teams.Resolver {
+	g := github.NewClient(nil)
+	u, err := url.Parse(s.BaseURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g.BaseURL = u
+	return &teams.Resolver{G: g, TTL: time.Minute, Clock: clock}
+}
+
+func TestResolverIsMember(t *testing.T) {
+	s := githubtest.NewServer()
+	defer s.Close()
+
+	s.SetTeam("garukun", "platform-team", "Alice", "bob")
+	s.SetTeam("garukun", "web", "carol")
+
+	clock := clocktest.New(time.Now())
+	r := newResolver(t, s, clock)
+
+	tests := []struct {
+		org, slug, user string
+		expected        bool
+		err             bool
+	}{
+		{"garukun", "platform-team", "alice", true, false},
+		{"garukun", "Platform-Team", "BOB", true, false},
+		{"garukun", "platform-team", "carol", false, false},
+		{"garukun", "web", "carol", true, false},
+		{"garukun", "unknown", "alice", false, true},
+		{"other", "web", "carol", false, true},
+	}
+
+	for i, test := range tests {
+		t.Logf("Testing %d...", i)
+
+		member, err := r.IsMember(test.org, test.slug, test.user)
+		if test.err != (err != nil) {
+			t.Errorf("Expecting error %t, but got %v.", test.err, err)
+		}
+
+		if member != test.expected {
+			t.Errorf("Expecting member %t, but got %t.", test.expected, member)
+		}
+	}
+}
+
+func TestResolverTTL(t *testing.T) {
+	s := githubtest.NewServer()
+	defer s.Close()
+
+	s.SetTeam("garukun", "platform-team", "alice")
+
+	clock := clocktest.New(time.Now())
+	r := newResolver(t, s, clock)
+
+	if ok, err := r.IsMember("garukun", "platform-team", "alice"); !ok || err != nil {
+		t.Fatalf("Expecting alice to be a member, but got %t, %v.", ok, err)
+	}
+
+	s.SetTeam("garukun", "platform-team", "bob")
+
+	// The members are cached until the TTL passes.
+	if ok, _ := r.IsMember("garukun", "platform-team", "bob"); ok {
+		t.Error("Expecting cached members without bob.")
+	}
+
+	clock.Add(time.Minute)
+
+	if ok, _ := r.IsMember("garukun", "platform-team", "bob"); !ok {
+		t.Error("Expecting refreshed members with bob.")
+	}
+
+	if ok, _ := r.IsMember("garukun", "platform-team", "alice"); ok {
+		t.Error("Expecting refreshed members without alice.")
+	}
+}
+
+func TestResolverError(t *testing.T) {
+	s := githubtest.NewServer()
+	defer s.Close()
+
+	clock := clocktest.New(time.Now())
+	r := newResolver(t, s, clock)
+
+	if _, err := r.IsMember("garukun", "platform-team", "alice"); err == nil {
+		t.Fatal("Expecting an error for an unknown team.")
+	}
+
+	// Failures are not cached.
+	s.SetTeam("garukun", "platform-team", "alice")
+
+	if ok, err := r.IsMember("garukun", "platform-team", "alice"); !ok || err != nil {
+		t.Errorf("Expecting alice to be a member, but got %t, %v.", ok, err)
+	}
+}
+
+func TestResolverConcurrent(t *testing.T) {
+	s := githubtest.NewServer()
+	defer s.Close()
+
+	s.SetTeam("garukun", "platform-team", "alice")
+
+	clock := clocktest.New(time.Now())
+	r := newResolver(t, s, clock)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, err := r.IsMember("garukun", "platform-team", "alice"); !ok || err != nil {
+				t.Errorf("Expecting alice to be a member, but got %t, %v.", ok, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
@@ -2,23 +2,41 @@ package lgtm
 
 import (
 	"context"
+	"fmt"
+	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/garukun/golgtm/pkg/http/httpadapter"
 	"github.com/garukun/golgtm/pkg/lgtm/config"
 	"github.com/garukun/golgtm/pkg/lgtm/internal/adapters"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/admin"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/codeowners"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/dashboard"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/delivery"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/githubapp"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/owners"
 	"github.com/garukun/golgtm/pkg/lgtm/internal/pr"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/reconcile"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/reminder"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/repoconfig"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/teams"
 	"github.com/google/go-github/github"
+	bolt "go.etcd.io/bbolt"
 	"golang.org/x/oauth2"
 )
 
 // Github Event types; see https://developer.github.com/webhooks/#events.
 const (
-	issueCommentEvent = "issue_comment"
-	pullRequestEvent  = "pull_request"
-	pingEvent         = "ping"
+	issueCommentEvent      = "issue_comment"
+	pullRequestEvent       = "pull_request"
+	pullRequestReviewEvent = "pull_request_review"
+	pushEvent              = "push"
+	pingEvent              = "ping"
 )
 
 // LGTM implements an http.Handler interface and handles incoming GitHub webhook requests to process
@@ -31,7 +49,14 @@ const (
 // Regardless of the state of the PR, LGTM will manage the lifecycle of the above two states and
 // provide relevant webhook context that can be used to gate from the PR being merged.
 type LGTM struct {
-	h http.Handler
+	h         http.Handler
+	admin     http.Handler
+	dashboard http.Handler
+	u         *pr.Updater
+
+	reminders *reminder.Scheduler  // Nil if reminders are off.
+	reconcile *reconcile.Scheduler // Nil if reconciliation is off.
+	db        *bolt.DB             // Nil if the review states are kept in memory.
 
 	G *github.Client
 
@@ -42,48 +67,282 @@ func (l *LGTM) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
 	l.h.ServeHTTP(resp, req)
 }
 
-func New(c *http.Client, conf *config.Config) *LGTM {
-	ctx := context.WithValue(oauth2.NoContext, oauth2.HTTPClient, c)
-	oc := oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: conf.Github.AuthToken}))
+// Admin method returns the http.Handler of the administrative API, which should not be exposed
+// publicly; see package admin for the routes.
+func (l *LGTM) Admin() http.Handler {
+	return l.admin
+}
+
+// Dashboard method returns the http.Handler of the HTML page of the review states of the open PRs;
+// see package dashboard for the filters.
+func (l *LGTM) Dashboard() http.Handler {
+	return l.dashboard
+}
+
+func New(c *http.Client, conf *config.Config) (*LGTM, error) {
+	oc, app, err := githubClient(c, conf)
+	if err != nil {
+		return nil, err
+	}
 
 	g := github.NewClient(oc)
+	if err := setURLs(g, conf); err != nil {
+		return nil, err
+	}
+
 	confCopy := *conf
 
+	events := map[string]httpadapter.Adapter{
+		pingEvent: adapters.Ping{},
+	}
+
+	db, store, deliveryStore, err := openStores(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	var audit pr.AuditLog
+	if len(conf.AuditPath) != 0 {
+		if audit, err = pr.NewFileAuditLog(conf.AuditPath); err != nil {
+			store.Close()
+			if db != nil {
+				db.Close()
+			}
+
+			return nil, err
+		}
+	}
+
 	u := &pr.Updater{
-		Logger: log.New(os.Stdout, "updater", log.LstdFlags),
-		G:      g,
-		Config: &confCopy,
+		Logger:     log.New(os.Stdout, "updater", log.LstdFlags),
+		G:          g,
+		Config:     &confCopy,
+		Owners:     &owners.Resolver{G: g},
+		CodeOwners: &codeowners.Resolver{G: g},
+		Teams:      &teams.Resolver{G: g, TTL: conf.Updater.TeamsTTL},
+		Store:      store,
+		Audit:      audit,
+		Retry: pr.Retry{
+			Attempts: conf.Updater.RetryAttempts,
+			Delay:    conf.Updater.RetryDelay,
+			MaxDelay: conf.Updater.RetryMaxDelay,
+		},
+		Checks:    conf.Updater.Checks,
+		Workers:   conf.Updater.Workers,
+		QueueSize: conf.Updater.QueueSize,
 	}
+
+	if confCopy.Github.RepoConfig {
+		u.Workflows = &repoconfig.Cache{
+			G:      g,
+			Config: &confCopy,
+		}
+
+		events[pushEvent] = &adapters.Push{
+			Workflows: u.Workflows,
+			Config:    &confCopy,
+		}
+	}
+
 	u.Start()
 
+	events[issueCommentEvent] = &adapters.IssueComment{
+		Updater: u,
+		Config:  &confCopy,
+	}
+	events[pullRequestEvent] = &adapters.PullRequest{
+		Updater: u,
+		Config:  &confCopy,
+		G:       g,
+	}
+	events[pullRequestReviewEvent] = &adapters.PullRequestReview{
+		Updater: u,
+		Config:  &confCopy,
+		G:       g,
+	}
+
 	l := &LGTM{
 		G:      g,
 		Config: confCopy,
+		db:     db,
 	}
-	h := adapters.Adapt(
-		http.NotFoundHandler(),
-
-		&adapters.Validator{Secret: []byte(conf.Github.Secret)},
-		&adapters.EventRouter{
-			Events: map[string]httpadapter.Adapter{
-				pingEvent: adapters.Ping{},
-				issueCommentEvent: &adapters.IssueComment{
-					Updater: u,
-					Config:  &confCopy,
-				},
-				pullRequestEvent: &adapters.PullRequest{
-					Updater: u,
-					Config:  &confCopy,
-					G:       g,
-				},
-			},
-		},
-	)
+	// The last adapter handles the request first.
+	chain := []httpadapter.Adapter{&adapters.EventRouter{Events: events}}
+	if app != nil {
+		chain = append(chain, &adapters.Installation{Installations: app})
+	}
+
+	if conf.Github.DeliveryTTL > 0 {
+		deliveries := &delivery.Cache{
+			TTL:   conf.Github.DeliveryTTL,
+			Size:  conf.Github.DeliveryCacheSize,
+			Store: deliveryStore,
+		}
+
+		// Only the validated deliveries are recorded.
+		chain = append(chain, &adapters.Dedupe{Deliveries: deliveries})
+	}
+
+	h := adapters.Adapt(http.NotFoundHandler(), append(chain, validator(conf))...)
 
 	l.h = h
-	return l
+	l.admin = admin.New(u)
+	l.dashboard = dashboard.New(u, nil)
+	l.u = u
+
+	if conf.Reminders.IdleAfter > 0 {
+		l.reminders = &reminder.Scheduler{Updater: u}
+		l.reminders.Start()
+	}
+
+	if conf.Updater.ReconcileInterval > 0 {
+		l.reconcile = &reconcile.Scheduler{Updater: u, Interval: conf.Updater.ReconcileInterval}
+		l.reconcile.Start()
+	}
+
+	return l, nil
+}
+
+// Close method stops the reminders, the reconciliation and accepting webhook updates, and waits for
+// the queued updates to be applied to GitHub until the context is done, then closes the stores of
+// the review states and of the delivery IDs, and the audit log.
+func (l *LGTM) Close(ctx context.Context) error {
+	var err error
+	if l.reminders != nil {
+		err = l.reminders.Close(ctx)
+	}
+
+	if l.reconcile != nil {
+		if rerr := l.reconcile.Close(ctx); err == nil {
+			err = rerr
+		}
+	}
+
+	if uerr := l.u.Close(ctx); err == nil {
+		err = uerr
+	}
+
+	if serr := l.u.Store.Close(); err == nil {
+		err = serr
+	}
+
+	if l.db != nil {
+		if derr := l.db.Close(); err == nil {
+			err = derr
+		}
+	}
+
+	if l.u.Audit != nil {
+		if aerr := l.u.Audit.Close(); err == nil {
+			err = aerr
+		}
+	}
+
+	return err
+}
+
+// openStores function returns the stores of the review states and of the webhook delivery IDs,
+// which share the BoltDB file at the store path if configured. Otherwise the review states are kept
+// in memory, the delivery IDs are only kept by the cache of the recent ones, and the BoltDB is nil.
+func openStores(conf *config.Config) (*bolt.DB, pr.Store, delivery.Store, error) {
+	if len(conf.StorePath) == 0 {
+		return nil, pr.NewMemoryStore(), nil, nil
+	}
+
+	db, err := bolt.Open(conf.StorePath, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	store, err := pr.NewBoltStore(db)
+	if err != nil {
+		db.Close()
+		return nil, nil, nil, err
+	}
+
+	deliveries, err := delivery.NewBoltStore(db)
+	if err != nil {
+		db.Close()
+		return nil, nil, nil, err
+	}
+
+	return db, store, deliveries, nil
+}
+
+// githubClient function returns the http.Client authenticating the GitHub API requests as a user,
+// or as the installations of the GitHub App if configured.
+func githubClient(c *http.Client, conf *config.Config) (*http.Client, *githubapp.App, error) {
+	if conf.Github.AppID == 0 {
+		ctx := context.WithValue(oauth2.NoContext, oauth2.HTTPClient, c)
+		return oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: conf.Github.AuthToken})), nil, nil
+	}
+
+	key, err := ioutil.ReadFile(conf.Github.AppKeyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	app, err := githubapp.New(conf.Github.AppID, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", conf.Github.AppKeyFile, err)
+	}
+
+	app.Client = c
+	if len(conf.Github.BaseURL) != 0 {
+		app.BaseURL = withSlash(conf.Github.BaseURL)
+	}
+
+	return &http.Client{Transport: app.Transport(c.Transport), Timeout: c.Timeout}, app, nil
+}
+
+// setURLs function points the GitHub client to the configured GitHub Enterprise instance, if any.
+func setURLs(g *github.Client, conf *config.Config) error {
+	urls := []struct {
+		dst **url.URL
+		src string
+	}{
+		{&g.BaseURL, conf.Github.BaseURL},
+		{&g.UploadURL, conf.Github.UploadURL},
+	}
+
+	for _, u := range urls {
+		if len(u.src) == 0 {
+			continue
+		}
+
+		parsed, err := url.Parse(withSlash(u.src))
+		if err != nil {
+			return err
+		}
+
+		*u.dst = parsed
+	}
+
+	return nil
+}
+
+// withSlash function returns the URL with a trailing slash, which the GitHub client requires.
+func withSlash(u string) string {
+	if strings.HasSuffix(u, "/") {
+		return u
+	}
+
+	return u + "/"
+}
+
+func validator(conf *config.Config) *adapters.Validator {
+	v := &adapters.Validator{AllowSHA1: conf.Github.AllowSHA1}
+	for _, secret := range conf.Github.Secrets {
+		v.Secrets = append(v.Secrets, []byte(secret))
+	}
+
+	return v
 }
 
 func ConfigFromEnv() (*config.Config, error) {
 	return config.NewFromEnv()
 }
+
+func ConfigFromFile(path string) (*config.Config, error) {
+	return config.NewFromFile(path)
+}
@@ -0,0 +1,382 @@
+package lgtm_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/garukun/golgtm/pkg/lgtm"
+	"github.com/garukun/golgtm/pkg/lgtm/internal/githubtest"
+)
+
+const testConf = `
+github:
+  secrets:
+  - matrix
+  auth_token: keymaker
+  repos:
+  - owner: garukun
+    name: golgtm
+  base_url: %s
+  upload_url: %s
+updater:
+  retry_attempts: 3
+  retry_delay: 1ms
+  retry_max_delay: 10ms
+%s`
+
+// newLGTM function returns LGTM using the fake GitHub API server, with the extra updater settings.
+func newLGTM(t *testing.T, s *githubtest.Server, updater string) *lgtm.LGTM {
+	dir, err := ioutil.TempDir("", "lgtm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "lgtm.yaml")
+	if err := ioutil.WriteFile(path, []byte(fmt.Sprintf(testConf, s.BaseURL(), s.BaseURL(), updater)), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	conf, err := lgtm.ConfigFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := lgtm.New(http.DefaultClient, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return l
+}
+
+// withApp function configures LGTM as a GitHub App through the environment variables, and returns
+// the function undoing so.
+func withApp(t *testing.T) func() {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := ioutil.TempFile("", "lgtm")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = pem.Encode(f, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("LGTM_GITHUB_APP_ID", "42")
+	os.Setenv("LGTM_GITHUB_APP_KEY_FILE", f.Name())
+
+	return func() {
+		os.Unsetenv("LGTM_GITHUB_APP_ID")
+		os.Unsetenv("LGTM_GITHUB_APP_KEY_FILE")
+		os.Remove(f.Name())
+	}
+}
+
+type delivery struct {
+	event   string
+	payload func(p githubtest.Pull) interface{}
+	push    string // Moves the head of the PR to the commit before the delivery.
+	code    int
+}
+
+func comment(user, body string) delivery {
+	return delivery{
+		event: "issue_comment",
+		payload: func(p githubtest.Pull) interface{} {
+			return githubtest.IssueCommentEvent("garukun", "golgtm", p, user, body)
+		},
+		code: http.StatusOK,
+	}
+}
+
+func deleteComment(user, body string) delivery {
+	return delivery{
+		event: "issue_comment",
+		payload: func(p githubtest.Pull) interface{} {
+			e := githubtest.IssueCommentEvent("garukun", "golgtm", p, user, body)
+			e["action"] = "deleted"
+			return e
+		},
+		code: http.StatusNoContent,
+	}
+}
+
+func synchronize(sha string) delivery {
+	return delivery{
+		event: "pull_request",
+		payload: func(p githubtest.Pull) interface{} {
+			return githubtest.PullRequestEvent("garukun", "golgtm", p, "synchronize", "neo")
+		},
+		push: sha,
+		code: http.StatusOK,
+	}
+}
+
+func closed() delivery {
+	return delivery{
+		event: "pull_request",
+		payload: func(p githubtest.Pull) interface{} {
+			return githubtest.PullRequestEvent("garukun", "golgtm", p, "closed", "neo")
+		},
+		code: http.StatusOK,
+	}
+}
+
+func review(user, state string) delivery {
+	return delivery{
+		event: "pull_request_review",
+		payload: func(p githubtest.Pull) interface{} {
+			return githubtest.PullRequestReviewEvent("garukun", "golgtm", p, "submitted", state, user)
+		},
+		code: http.StatusOK,
+	}
+}
+
+func TestWebhookFlows(t *testing.T) {
+	tests := []struct {
+		faults      []githubtest.Fault
+		deliveries  []delivery
+		labels      []string
+		status      string // Status state on the head commit; no status if empty.
+		comments    int
+		deadLetters int
+	}{
+		// Approved by a comment, keeping the other labels. Comments do not tell the head commit, so
+		// the status is left as is.
+		{
+			deliveries: []delivery{comment("trinity", "LGTM")},
+			labels:     []string{"bug", "Ready"},
+		},
+		// Not a trigger.
+		{
+			deliveries: []delivery{
+				func() delivery { d := comment("trinity", "Nice!"); d.code = http.StatusNoContent; return d }(),
+			},
+			labels: []string{"bug"},
+		},
+		// Deleting a comment is not a vote.
+		{
+			deliveries: []delivery{deleteComment("trinity", "lgtm")},
+			labels:     []string{"bug"},
+		},
+		// Back to review after approval.
+		{
+			deliveries: []delivery{comment("trinity", "lgtm"), comment("morpheus", "ptal")},
+			labels:     []string{"bug", "Needs Review"},
+		},
+		// New commits revert the approval with a comment.
+		{
+			deliveries: []delivery{comment("trinity", "lgtm"), synchronize("c0ffee")},
+			labels:     []string{"bug", "Needs Review"},
+			status:     "pending",
+			comments:   1,
+		},
+		// Closing forgets the PR, leaving the labels as they are.
+		{
+			deliveries: []delivery{comment("trinity", "lgtm"), closed()},
+			labels:     []string{"bug", "Ready"},
+		},
+		// Approved by a review.
+		{
+			deliveries: []delivery{review("trinity", "approved")},
+			labels:     []string{"bug", "Ready"},
+			status:     "success",
+		},
+		// Server errors are retried.
+		{
+			faults:     []githubtest.Fault{githubtest.ServerError("PUT", "/repos/garukun/golgtm/issues/1/labels", 2)},
+			deliveries: []delivery{review("trinity", "approved")},
+			labels:     []string{"bug", "Ready"},
+			status:     "success",
+		},
+		// Exhausted rate limits are retried once they reset...
+		{
+			faults:     []githubtest.Fault{githubtest.RateLimited("PUT", "/repos/garukun/golgtm/issues/1/labels", time.Now(), 1)},
+			deliveries: []delivery{comment("trinity", "lgtm")},
+			labels:     []string{"bug", "Ready"},
+		},
+		// ...until the attempts run out, and the update is kept as a dead letter.
+		{
+			faults:      []githubtest.Fault{githubtest.RateLimited("PUT", "/repos/garukun/golgtm/issues/1/labels", time.Now(), 0)},
+			deliveries:  []delivery{comment("trinity", "lgtm")},
+			labels:      []string{"bug"},
+			deadLetters: 1,
+		},
+		// Failing to read the PR fails the webhook.
+		{
+			faults: []githubtest.Fault{githubtest.ServerError("GET", "/repos/garukun/golgtm/issues/1", 0)},
+			deliveries: []delivery{
+				func() delivery { d := synchronize("c0ffee"); d.code = http.StatusNoContent; return d }(),
+			},
+			labels: []string{"bug"},
+		},
+	}
+
+	for i, test := range tests {
+		t.Logf("Testing %d...", i)
+
+		s := githubtest.NewServer()
+		s.AddPull("garukun", "golgtm", githubtest.Pull{
+			Number:  1,
+			Author:  "neo",
+			HeadSHA: "deadbeef",
+			BaseSHA: "cafe",
+			Labels:  []string{"bug"},
+		})
+
+		for _, f := range test.faults {
+			s.Inject(f)
+		}
+
+		l := newLGTM(t, s, "")
+		for j, d := range test.deliveries {
+			if len(d.push) != 0 {
+				s.Push("garukun", "golgtm", 1, d.push)
+			}
+
+			p, _ := s.Pull("garukun", "golgtm", 1)
+			resp := httptest.NewRecorder()
+			l.ServeHTTP(resp, githubtest.Delivery("matrix", d.event, d.payload(p)))
+
+			if resp.Code != d.code {
+				t.Errorf("Expected delivery %d to respond %d instead of %d.", j, d.code, resp.Code)
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := l.Close(ctx); err != nil {
+			t.Errorf("Unexpected error %v closing LGTM.", err)
+		}
+		cancel()
+
+		// The comment reverting the review status is posted asynchronously.
+		p, _ := s.Pull("garukun", "golgtm", 1)
+		for deadline := time.Now().Add(time.Second); len(p.Comments) < test.comments && time.Now().Before(deadline); {
+			time.Sleep(10 * time.Millisecond)
+			p, _ = s.Pull("garukun", "golgtm", 1)
+		}
+
+		if !reflect.DeepEqual(p.Labels, test.labels) {
+			t.Errorf("Expected labels %v instead of %v.", test.labels, p.Labels)
+		}
+
+		st, ok := s.Status("garukun", "golgtm", p.HeadSHA, "LGTM Code Review")
+		if ok != (len(test.status) != 0) || st.State != test.status {
+			t.Errorf("Expected status %q on %s instead of %q.", test.status, p.HeadSHA, st.State)
+		}
+
+		if len(p.Comments) != test.comments {
+			t.Errorf("Expected %d comment(s) instead of %v.", test.comments, p.Comments)
+		}
+
+		if n := deadLetters(t, l); n != test.deadLetters {
+			t.Errorf("Expected %d dead letter(s) instead of %d.", test.deadLetters, n)
+		}
+
+		s.Close()
+	}
+}
+
+func TestChecks(t *testing.T) {
+	tests := []struct {
+		deliveries []delivery
+		status     string
+		conclusion string
+		summary    string // Part of the summary.
+	}{
+		{
+			deliveries: []delivery{review("trinity", "approved")},
+			status:     "completed",
+			conclusion: "success",
+			summary:    "- @trinity approved by a review\n",
+		},
+		{
+			deliveries: []delivery{synchronize("c0ffee")},
+			status:     "in_progress",
+			summary:    "#### Outstanding requirements\nAny of `lgtm` from 1 user(s), `:+1:` from 1 user(s).\n",
+		},
+		// A completed check run is taken over by a new one in progress.
+		{
+			deliveries: []delivery{review("trinity", "approved"), review("morpheus", "changes_requested")},
+			status:     "in_progress",
+			summary:    "@morpheus: `changes_requested`",
+		},
+	}
+
+	// Only GitHub Apps may use the Checks API.
+	defer withApp(t)()
+
+	for i, test := range tests {
+		t.Logf("Testing %d...", i)
+
+		s := githubtest.NewServer()
+		s.AddPull("garukun", "golgtm", githubtest.Pull{Number: 1, Author: "neo", HeadSHA: "deadbeef"})
+
+		l := newLGTM(t, s, "  checks: true\n")
+		for _, d := range test.deliveries {
+			if len(d.push) != 0 {
+				s.Push("garukun", "golgtm", 1, d.push)
+			}
+
+			p, _ := s.Pull("garukun", "golgtm", 1)
+			l.ServeHTTP(httptest.NewRecorder(), githubtest.Delivery("matrix", d.event, d.payload(p)))
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := l.Close(ctx); err != nil {
+			t.Errorf("Unexpected error %v closing LGTM.", err)
+		}
+		cancel()
+
+		p, _ := s.Pull("garukun", "golgtm", 1)
+		run, ok := s.CheckRun("garukun", "golgtm", p.HeadSHA, "LGTM Code Review")
+		if !ok {
+			t.Errorf("Expected a check run on %s.", p.HeadSHA)
+		} else if run.Status != test.status || run.Conclusion != test.conclusion {
+			t.Errorf("Expected check run %s %s instead of %s %s.", test.status, test.conclusion, run.Status, run.Conclusion)
+		} else if !strings.Contains(run.Output.Summary, test.summary) {
+			t.Errorf("Expected %q in the summary:\n%s", test.summary, run.Output.Summary)
+		}
+
+		if _, ok := s.Status("garukun", "golgtm", p.HeadSHA, "LGTM Code Review"); ok {
+			t.Errorf("Expected no commit status on %s.", p.HeadSHA)
+		}
+
+		s.Close()
+	}
+}
+
+func deadLetters(t *testing.T, l *lgtm.LGTM) int {
+	resp := httptest.NewRecorder()
+	l.Admin().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/deadletters", nil))
+
+	var letters []interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&letters); err != nil {
+		t.Errorf("Unexpected error %v decoding dead letters.", err)
+	}
+
+	return len(letters)
+}